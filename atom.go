@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+type AtomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    AtomText    `xml:"http://www.w3.org/2005/Atom title"`
+	Subtitle AtomText    `xml:"http://www.w3.org/2005/Atom subtitle"`
+	Link     []AtomLink  `xml:"http://www.w3.org/2005/Atom link"`
+	Entry    []AtomEntry `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type AtomEntry struct {
+	ID        string         `xml:"http://www.w3.org/2005/Atom id"`
+	Title     AtomText       `xml:"http://www.w3.org/2005/Atom title"`
+	Link      []AtomLink     `xml:"http://www.w3.org/2005/Atom link"`
+	Published string         `xml:"http://www.w3.org/2005/Atom published"`
+	Updated   string         `xml:"http://www.w3.org/2005/Atom updated"`
+	Summary   AtomText       `xml:"http://www.w3.org/2005/Atom summary"`
+	Content   AtomText       `xml:"http://www.w3.org/2005/Atom content"`
+	Author    []AtomPerson   `xml:"http://www.w3.org/2005/Atom author"`
+	Category  []AtomCategory `xml:"http://www.w3.org/2005/Atom category"`
+	// ItemMedia reads the Media RSS elements of e.g. YouTube feeds.
+	ItemMedia
+}
+
+// UnmarshalXML decodes the Atom elements of an entry from the Atom
+// namespace, or from none for documents that leave it out as decodeAtom
+// allows, so that they are not lost to the namespaced struct tags.
+func (entry *AtomEntry) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	return decodeChildren(decoder, func(start xml.StartElement) error {
+		switch start.Name.Space {
+		case atomNS, "":
+			return entry.decodeAtom(decoder, start)
+		case itunesNS, mediaNS:
+			return entry.ItemMedia.decode(decoder, start)
+		}
+		return decoder.Skip()
+	})
+}
+
+func (entry *AtomEntry) decodeAtom(decoder *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+	case "id":
+		return decoder.DecodeElement(&entry.ID, &start)
+	case "title":
+		return decoder.DecodeElement(&entry.Title, &start)
+	case "link":
+		var link AtomLink
+		err := decoder.DecodeElement(&link, &start)
+		entry.Link = append(entry.Link, link)
+		return err
+	case "published":
+		return decoder.DecodeElement(&entry.Published, &start)
+	case "updated":
+		return decoder.DecodeElement(&entry.Updated, &start)
+	case "summary":
+		return decoder.DecodeElement(&entry.Summary, &start)
+	case "content":
+		return decoder.DecodeElement(&entry.Content, &start)
+	case "author":
+		var author AtomPerson
+		err := decoder.DecodeElement(&author, &start)
+		entry.Author = append(entry.Author, author)
+		return err
+	case "category":
+		var category AtomCategory
+		err := decoder.DecodeElement(&category, &start)
+		entry.Category = append(entry.Category, category)
+		return err
+	}
+	return decoder.Skip()
+}
+
+type AtomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type AtomPerson struct {
+	Name string `xml:"http://www.w3.org/2005/Atom name"`
+}
+
+// UnmarshalXML reads the name of a person from the Atom namespace or from
+// none, like AtomEntry.
+func (p *AtomPerson) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	return decodeChildren(decoder, func(start xml.StartElement) error {
+		if (start.Name.Space == atomNS || start.Name.Space == "") && start.Name.Local == "name" {
+			return decoder.DecodeElement(&p.Name, &start)
+		}
+		return decoder.Skip()
+	})
+}
+
+// AtomCategory is a category of an entry; the label, if given, is the
+// human-readable form of the term.
+type AtomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+// AtomText is an Atom text construct. Plain text and escaped HTML are read
+// as character data; XHTML content is kept as its inner markup.
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Body  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Body)
+}
+
+// alternateLink picks the rel="alternate" link, preferring an HTML one.
+// A link without a rel attribute is an alternate link per RFC 4287.
+func alternateLink(links []AtomLink) string {
+	href := ""
+	for _, link := range links {
+		if link.Rel != "" && link.Rel != "alternate" {
+			continue
+		}
+		if link.Type == "" || link.Type == "text/html" {
+			return link.Href
+		}
+		if href == "" {
+			href = link.Href
+		}
+	}
+	return href
+}
+
+func (f *AtomFeed) normalize() *ParsedFeed {
+	feed := &ParsedFeed{
+		Title:       f.Title.String(),
+		Link:        alternateLink(f.Link),
+		Description: f.Subtitle.String(),
+	}
+	for _, entry := range f.Entry {
+		description := entry.Summary.String()
+		if description == "" {
+			description = entry.Content.String()
+		}
+		pubDate := entry.Published
+		if pubDate == "" {
+			pubDate = entry.Updated
+		}
+		var enclosures []FeedEnclosure
+		commentsURL := ""
+		for _, link := range entry.Link {
+			switch link.Rel {
+			case "enclosure":
+				enclosures = append(enclosures, FeedEnclosure{
+					URL:      strings.TrimSpace(link.Href),
+					MimeType: strings.TrimSpace(link.Type),
+					Length:   parseLength(link.Length),
+				})
+			case "replies":
+				// RFC 4685 reply links; prefer the comments page over a
+				// comments feed
+				if commentsURL == "" || link.Type == "text/html" {
+					commentsURL = strings.TrimSpace(link.Href)
+				}
+			}
+		}
+		var authors []string
+		for _, author := range entry.Author {
+			authors = append(authors, author.Name)
+		}
+		var categories []string
+		for _, category := range entry.Category {
+			name := category.Label
+			if name == "" {
+				name = category.Term
+			}
+			if name = strings.TrimSpace(name); name != "" {
+				categories = append(categories, name)
+			}
+		}
+		feed.Items = append(feed.Items, FeedItem{
+			GUID:        strings.TrimSpace(entry.ID),
+			Title:       entry.Title.String(),
+			Link:        alternateLink(entry.Link),
+			Description: description,
+			Content:     entry.Content.String(),
+			PubDate:     strings.TrimSpace(pubDate),
+			Author:      joinNonEmpty(authors, ", "),
+			Categories:  categories,
+			CommentsURL: commentsURL,
+			Enclosures:  entry.ItemMedia.enclosures(enclosures),
+		})
+	}
+	return feed
+}
+
+func decodeAtom(decoder *xml.Decoder, limit *itemLimit) (*ParsedFeed, error) {
+	var atom AtomFeed
+	err := decodeChildren(decoder, func(start xml.StartElement) error {
+		if start.Name.Space != atomNS && start.Name.Space != "" {
+			return decoder.Skip()
+		}
+		switch start.Name.Local {
+		case "title":
+			return decoder.DecodeElement(&atom.Title, &start)
+		case "subtitle":
+			return decoder.DecodeElement(&atom.Subtitle, &start)
+		case "link":
+			var link AtomLink
+			err := decoder.DecodeElement(&link, &start)
+			atom.Link = append(atom.Link, link)
+			return err
+		case "entry":
+			entry, err := decodeItem[AtomEntry](decoder, start, limit)
+			if err != nil {
+				return err
+			}
+			atom.Entry = append(atom.Entry, entry)
+			return nil
+		}
+		return decoder.Skip()
+	})
+	return atom.normalize(), err
+}
@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestParseAtomEntries(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{
+			name: "Atom namespace",
+			doc: `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/"><title>Feed</title>
+<entry><id>urn:1</id><title>First</title><link href="https://example.com/1"/><updated>2006-01-02T15:04:05Z</updated>
+<content type="html">Body</content><author><name>Ann</name></author><category term="go"/>
+<media:title>Other title</media:title><media:content url="https://example.com/1.mp4" type="video/mp4"/></entry></feed>`,
+		},
+		{
+			name: "no namespace",
+			doc: `<feed><title>Feed</title>
+<entry><id>urn:1</id><title>First</title><link href="https://example.com/1"/><updated>2006-01-02T15:04:05Z</updated>
+<content type="html">Body</content><author><name>Ann</name></author><category term="go"/>
+<media:content xmlns:media="http://search.yahoo.com/mrss/" url="https://example.com/1.mp4" type="video/mp4"/></entry></feed>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.doc), "application/atom+xml", 10)
+			if err != nil {
+				t.Fatalf("parseFeed failed: %v", err)
+			}
+			if feed.Title != "Feed" || len(feed.Items) != 1 {
+				t.Fatalf("feed = %+v, want one entry in a feed titled Feed", feed)
+			}
+			item := feed.Items[0]
+			if item.GUID != "urn:1" || item.Title != "First" || item.Link != "https://example.com/1" || item.Content != "Body" {
+				t.Errorf("item = %+v", item)
+			}
+			if item.Author != "Ann" || len(item.Categories) != 1 || item.PubDate != "2006-01-02T15:04:05Z" {
+				t.Errorf("item = %+v", item)
+			}
+			if len(item.Enclosures) != 1 || item.Enclosures[0].MimeType != "video/mp4" {
+				t.Errorf("enclosures = %+v, want the media:content video", item.Enclosures)
+			}
+		})
+	}
+}
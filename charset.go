@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+var xmlDeclEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// documentCharset returns the charset label of a feed document: from a byte
+// order mark, then the Content-Type header, then the XML declaration.
+// Documents that declare nothing are taken to be UTF-8.
+func documentCharset(dat []byte, contentType string) string {
+	switch {
+	case bytes.HasPrefix(dat, utf8BOM):
+		return "utf-8"
+	case bytes.HasPrefix(dat, []byte{0xfe, 0xff}):
+		return "utf-16be"
+	case bytes.HasPrefix(dat, []byte{0xff, 0xfe}):
+		return "utf-16le"
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+		return params["charset"]
+	}
+	head := dat[:min(len(dat), 1024)]
+	if m := xmlDeclEncoding.FindSubmatch(head); m != nil {
+		return string(m[1])
+	}
+	return "utf-8"
+}
+
+// toUTF8 transcodes a feed document to UTF-8 and strips any byte order mark.
+func toUTF8(dat []byte, contentType string) ([]byte, error) {
+	label := documentCharset(dat, contentType)
+	enc, name := charset.Lookup(label)
+	if enc == nil {
+		return nil, fmt.Errorf("unsupported charset: %s", label)
+	}
+	if name == "utf-8" {
+		return bytes.TrimPrefix(dat, utf8BOM), nil
+	}
+	// The decoder consumes a matching byte order mark itself.
+	decoded, err := enc.NewDecoder().Bytes(dat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s document: %w", name, err)
+	}
+	return bytes.TrimPrefix(decoded, utf8BOM), nil
+}
+
+// newXMLDecoder returns a decoder for a document already transcoded by
+// toUTF8. The XML declaration may still name the original encoding, which
+// the decoder would otherwise refuse. A non-strict decoder tolerates
+// unknown entities and missing end tags and resolves HTML entities such as
+// &nbsp;.
+func newXMLDecoder(dat []byte, strict bool) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(dat))
+	if !strict {
+		// HTMLAutoClose is deliberately not used: it would treat the
+		// RSS <link> element as an empty HTML <link>.
+		decoder.Strict = false
+		decoder.Entity = xml.HTMLEntity
+	}
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		if _, name := charset.Lookup(label); name == "" && !strings.EqualFold(label, "utf-8") {
+			return nil, fmt.Errorf("unsupported charset: %s", label)
+		}
+		return input, nil
+	}
+	return decoder
+}
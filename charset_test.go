@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func rssWithTitle(decl, title string) []byte {
+	return []byte(decl + `<rss version="2.0"><channel><title>` + title + `</title><item><title>` + title + `</title><link>https://example.com/1</link></item></channel></rss>`)
+}
+
+func TestParseFeedCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		doc         []byte
+		contentType string
+		want        string
+	}{
+		{
+			name: "ISO-8859-1 from the XML declaration",
+			doc:  rssWithTitle(`<?xml version="1.0" encoding="ISO-8859-1"?>`, "Caf\xe9 cr\xe8me"),
+			want: "Café crème",
+		},
+		{
+			name: "windows-1252 from the XML declaration",
+			doc:  rssWithTitle(`<?xml version="1.0" encoding="windows-1252"?>`, "\x93Quoted\x94 \x80 5"),
+			want: "“Quoted” € 5",
+		},
+		{
+			name: "Shift_JIS from the XML declaration",
+			doc:  rssWithTitle(`<?xml version="1.0" encoding="Shift_JIS"?>`, "\x93\xfa\x96{\x8c\xea"),
+			want: "日本語",
+		},
+		{
+			name:        "ISO-8859-1 from the Content-Type",
+			doc:         rssWithTitle(`<?xml version="1.0"?>`, "Caf\xe9"),
+			contentType: "application/rss+xml; charset=ISO-8859-1",
+			want:        "Café",
+		},
+		{
+			name:        "Content-Type charset overrides the XML declaration",
+			doc:         rssWithTitle(`<?xml version="1.0" encoding="UTF-8"?>`, "\x80 10"),
+			contentType: "application/rss+xml; charset=windows-1252",
+			want:        "€ 10",
+		},
+		{
+			name:        "Shift_JIS Content-Type overrides an ISO-8859-1 declaration",
+			doc:         rssWithTitle(`<?xml version="1.0" encoding="ISO-8859-1"?>`, "\x93\xfa\x96{"),
+			contentType: "text/xml; charset=Shift_JIS",
+			want:        "日本",
+		},
+		{
+			name:        "byte order mark overrides the Content-Type",
+			doc:         append([]byte("\xef\xbb\xbf"), rssWithTitle(`<?xml version="1.0" encoding="ISO-8859-1"?>`, "Café")...),
+			contentType: "application/rss+xml; charset=ISO-8859-1",
+			want:        "Café",
+		},
+		{
+			name: "UTF-8 when nothing is declared",
+			doc:  rssWithTitle("", "Café"),
+			want: "Café",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed(tt.doc, tt.contentType, 10)
+			if err != nil {
+				t.Fatalf("parseFeed failed: %v", err)
+			}
+			if feed.Title != tt.want {
+				t.Errorf("feed title = %q, want %q", feed.Title, tt.want)
+			}
+			if len(feed.Items) != 1 || feed.Items[0].Title != tt.want {
+				t.Errorf("items = %+v, want one titled %q", feed.Items, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedUnsupportedCharset(t *testing.T) {
+	doc := rssWithTitle(`<?xml version="1.0" encoding="x-unknown"?>`, "Title")
+	if _, err := parseFeed(doc, "", 10); err == nil {
+		t.Error("parseFeed succeeded, want an unsupported charset error")
+	}
+}
@@ -2,19 +2,33 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Corogura/gator/internal/config"
 	"github.com/Corogura/gator/internal/database"
+	"github.com/Corogura/gator/internal/dateparse"
 	"github.com/google/uuid"
 )
 
 type state struct {
-	db  *database.Queries
-	cfg *config.Config
+	db *database.Queries
+	// conn is the connection pool behind db, for running queries in a
+	// transaction via db.WithTx.
+	conn *sql.DB
+	cfg  *config.Config
+	// client is shared by every outgoing HTTP request.
+	client *http.Client
+	// limiter spaces out requests to the same host.
+	limiter *hostLimiters
+	// location is the time zone times are printed in.
+	location *time.Location
 }
 
 type command struct {
@@ -39,6 +53,34 @@ func handlerSetup(s *state, _ command) error {
 	if err != nil {
 		return fmt.Errorf("failed to setup posts: %w", err)
 	}
+	err = s.db.SetupPostSearchIndex(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to setup post search index: %w", err)
+	}
+	err = s.db.SetupEnclosures(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to setup enclosures: %w", err)
+	}
+	err = s.db.SetupPostCategories(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to setup post categories: %w", err)
+	}
+	err = s.db.SetupPostReads(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to setup post reads: %w", err)
+	}
+	err = s.db.SetupSavedPosts(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to setup saved posts: %w", err)
+	}
+	err = s.db.SetupDownloads(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to setup downloads: %w", err)
+	}
+	err = s.db.SetupFeedCredentials(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to setup feed credentials: %w", err)
+	}
 	fmt.Println("Database setup completed successfully")
 	return nil
 }
@@ -67,8 +109,8 @@ func handlerRegister(s *state, cmd command) error {
 		context.Background(),
 		database.CreateUserParams{
 			ID:        uuid.New(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
 			Name:      cmd.arg[0],
 		},
 	)
@@ -77,7 +119,7 @@ func handlerRegister(s *state, cmd command) error {
 	}
 	s.cfg.SetUser(cmd.arg[0])
 	fmt.Println("user registered successfully")
-	fmt.Printf("user id: %s, created_at: %v, updated_at: %v, name: %s\n", user.ID, user.CreatedAt, user.UpdatedAt, user.Name)
+	fmt.Printf("user id: %s, created_at: %s, updated_at: %s, name: %s\n", user.ID, formatTime(s, user.CreatedAt), formatTime(s, user.UpdatedAt), user.Name)
 	return nil
 }
 
@@ -109,43 +151,90 @@ func handlerAgg(s *state, cmd command) error {
 	if len(cmd.arg) < 1 {
 		return errors.New("enter time period (e.g., 1h, 1d, 1w)")
 	}
-	fmt.Printf("Collecting feeds every %s\n", cmd.arg[0])
 	timePeriod, err := time.ParseDuration(cmd.arg[0])
 	if err != nil {
 		return fmt.Errorf("invalid time period: %w", err)
 	}
+	if _, _, err := s.cfg.RefreshIntervals(); err != nil {
+		return err
+	}
+	concurrency := 1
+	if len(cmd.arg) > 1 {
+		concurrency, err = strconv.Atoi(cmd.arg[1])
+		if err != nil || concurrency < 1 {
+			return fmt.Errorf("invalid concurrency: %s", cmd.arg[1])
+		}
+	}
+	fmt.Printf("Collecting %d feed(s) every %s\n", concurrency, cmd.arg[0])
 	ticker := time.NewTicker(timePeriod)
 	for ; ; <-ticker.C {
-		scrapeFeeds(s)
+		for _, err := range scrapeFeeds(s, concurrency) {
+			fmt.Println(err)
+		}
 	}
 }
 
 func handlerAddFeed(s *state, cmd command, user database.User) error {
-	if len(cmd.arg) < 2 {
+	args, flags, err := parseFlags(cmd.arg, authBasic, authBearer, authQuery)
+	if err != nil {
+		return err
+	}
+	if len(args) < 2 {
 		return errors.New("enter feed name and URL")
 	}
-	parsedURL, err := normURL(cmd.arg[1])
+	parsedURL, err := normURL(args[1])
 	if err != nil {
 		return fmt.Errorf("invalid URL: %w", err)
 	}
+	var creds *feedCredentials
+	for authType, value := range flags {
+		if creds != nil {
+			return errors.New("use only one of --basic, --bearer and --query")
+		}
+		creds = &feedCredentials{Type: authType, Value: value}
+	}
+	// Check the credentials before creating the feed, so a bad value or a
+	// missing key does not leave a feed behind that cannot be fetched
+	if creds != nil {
+		if err := creds.validate(); err != nil {
+			return err
+		}
+		if _, err := credentialsKey(s); err != nil {
+			return err
+		}
+	}
+	feedURL := parsedURL.String()
+	// A private feed would turn away the discovery request, and its URL is
+	// known anyway
+	if creds == nil {
+		feedURL, err = chooseFeed(s, feedURL)
+		if err != nil {
+			return err
+		}
+	}
 	feed, err := s.db.CreateFeed(
 		context.Background(),
 		database.CreateFeedParams{
 			ID:        uuid.New(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			Name:      cmd.arg[0],
-			Url:       parsedURL.String(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			Name:      args[0],
+			Url:       feedURL,
 			UserID:    user.ID,
 		},
 	)
 	if err != nil {
 		return err
 	}
+	if creds != nil {
+		if err := saveFeedCredentials(s, feed.ID, *creds); err != nil {
+			return err
+		}
+	}
 	_, err = s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
 		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
 		FeedID:    feed.ID,
 		UserID:    user.ID,
 	})
@@ -166,8 +255,116 @@ func handlerFeeds(s *state, _ command) error {
 		if err != nil {
 			return fmt.Errorf("failed to get user for feed %s: %w", feed.Name, err)
 		}
-		fmt.Printf("Name: %s, URL: %s, Username: %s\n", feed.Name, feed.Url, user.Name)
+		// Only the kind of auth is shown, never the secret
+		auth := "none"
+		creds, err := s.db.GetFeedCredentials(context.Background(), feed.ID)
+		if err == nil {
+			auth = creds.AuthType
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to get credentials for feed %s: %w", feed.Name, err)
+		}
+		fmt.Printf("Name: %s, URL: %s, Username: %s, Auth: %s\n", feed.Name, feed.Url, user.Name, auth)
+	}
+	return nil
+}
+
+func handlerSetAuth(s *state, cmd command, user database.User) error {
+	if len(cmd.arg) < 2 {
+		return errors.New("enter feed url and auth type (basic, bearer, query or none)")
+	}
+	feed, err := s.db.GetFeedByURL(context.Background(), cmd.arg[0])
+	if err != nil {
+		return fmt.Errorf("failed to get feed by url: %w", err)
+	}
+	if feed.UserID != user.ID {
+		return errors.New("only the user who added the feed can change its credentials")
+	}
+	if cmd.arg[1] == "none" {
+		err = s.db.DeleteFeedCredentials(context.Background(), feed.ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete credentials: %w", err)
+		}
+		fmt.Printf("Credentials removed: %s\n", feed.Name)
+		return nil
+	}
+	creds := feedCredentials{Type: cmd.arg[1]}
+	if len(cmd.arg) > 2 {
+		creds.Value = cmd.arg[2]
+	} else {
+		// Reading the secret from stdin keeps it out of the shell history
+		creds.Value, err = readLine("Enter credentials: ")
+		if err != nil {
+			return fmt.Errorf("failed to read credentials: %w", err)
+		}
+	}
+	if err := saveFeedCredentials(s, feed.ID, creds); err != nil {
+		return err
+	}
+	fmt.Printf("Credentials set: %s (%s)\n", feed.Name, creds.Type)
+	return nil
+}
+
+func handlerFeedStatus(s *state, cmd command) error {
+	var feeds []database.Feed
+	if len(cmd.arg) > 0 {
+		feed, err := s.db.GetFeedByURL(context.Background(), cmd.arg[0])
+		if err != nil {
+			return fmt.Errorf("failed to get feed by url: %w", err)
+		}
+		feeds = append(feeds, feed)
+	} else {
+		var err error
+		feeds, err = s.db.GetFeeds(context.Background())
+		if err != nil {
+			return err
+		}
+	}
+	for _, feed := range feeds {
+		status := "ok"
+		if feed.GoneAt.Valid {
+			status = fmt.Sprintf("gone since %s", formatTime(s, feed.GoneAt.Time))
+		} else if feed.DisabledAt.Valid {
+			status = fmt.Sprintf("disabled since %s", formatTime(s, feed.DisabledAt.Time))
+		} else if feed.ConsecutiveFailures > 0 {
+			status = "failing"
+		}
+		fmt.Println("--------------------------------------------------")
+		fmt.Printf("Name: %s\nURL: %s\nStatus: %s\n", feed.Name, feed.Url, status)
+		if feed.LastFetchedAt.Valid {
+			fmt.Printf("Last Fetched At: %s\n", formatTime(s, feed.LastFetchedAt.Time))
+		} else {
+			fmt.Println("Last Fetched At: never")
+		}
+		if feed.NextFetchAt.Valid {
+			fmt.Printf("Next Fetch At: %s\n", formatTime(s, feed.NextFetchAt.Time))
+		}
+		fmt.Printf("Consecutive Failures: %d\n", feed.ConsecutiveFailures)
+		if feed.LastError.Valid {
+			fmt.Printf("Last Error: %s\nLast Error At: %s\n", feed.LastError.String, formatTime(s, feed.LastErrorAt.Time))
+		}
+		if feed.ParseWarning.Valid {
+			fmt.Printf("Parse Warning: %s\n", feed.ParseWarning.String)
+		}
+	}
+	return nil
+}
+
+func handlerEnableFeed(s *state, cmd command) error {
+	if len(cmd.arg) < 1 {
+		return errors.New("enter feed url to enable")
+	}
+	feed, err := s.db.GetFeedByURL(context.Background(), cmd.arg[0])
+	if err != nil {
+		return fmt.Errorf("failed to get feed by url: %w", err)
+	}
+	err = s.db.EnableFeed(context.Background(), database.EnableFeedParams{
+		ID:        feed.ID,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enable feed: %w", err)
 	}
+	fmt.Printf("Feed enabled: %s\n", feed.Name)
 	return nil
 }
 
@@ -180,13 +377,25 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("invalid URL: %w", err)
 	}
 	feed, err := s.db.GetFeedByURL(context.Background(), parsedURL.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		// The URL may be a web page that links to a feed that was added
+		var feedURL string
+		feedURL, err = chooseFeed(s, parsedURL.String())
+		if err != nil {
+			return err
+		}
+		feed, err = s.db.GetFeedByURL(context.Background(), feedURL)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("feed %s has not been added yet, add it with addfeed", feedURL)
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("failed to get feed by URL: %w", err)
 	}
 	feedFollow, err := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
 		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
 		FeedID:    feed.ID,
 		UserID:    user.ID,
 	})
@@ -229,27 +438,391 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 }
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
+	args, includeRead := cutSwitch(cmd.arg, "all")
+	args, flags, err := parseFlags(args, "category", "author")
+	if err != nil {
+		return err
+	}
 	limit := 2
-	if len(cmd.arg) > 0 {
-		var err error
-		limit, err = strconv.Atoi(cmd.arg[0])
+	if len(args) > 0 {
+		limit, err = strconv.Atoi(args[0])
 		if err != nil {
 			return fmt.Errorf("invalid limit: %w", err)
 		}
 	}
+	category, hasCategory := flags["category"]
+	author, hasAuthor := flags["author"]
 	posts, err := s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
 		UserID: user.ID,
-		Limit:  int32(limit),
+		Category: sql.NullString{
+			String: category,
+			Valid:  hasCategory,
+		},
+		Author: sql.NullString{
+			String: author,
+			Valid:  hasAuthor,
+		},
+		IncludeRead: includeRead,
+		MaxPosts:    int32(limit),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to get posts for user: %w", err)
 	}
+	if len(posts) == 0 && !includeRead {
+		fmt.Println("No unread posts")
+		return nil
+	}
 	for _, post := range posts {
+		description := post.Description
+		if description == "" {
+			description = post.Content
+		}
 		fmt.Println("--------------------------------------------------")
-		fmt.Printf("Title: %s\nURL: %s\nDescription: %s\nPublished At: %v\nFeed: %s\n",
-			post.Title, post.Url, post.Description, post.PublishedAt.Time, post.FeedName)
+		fmt.Printf("ID: %s\nTitle: %s\nURL: %s\nDescription: %s\nPublished At: %s\nFeed: %s\n",
+			post.ID, post.Title, post.Url, description, formatPublished(s, post.PublishedAt), post.FeedName)
+		if post.Author != "" {
+			fmt.Printf("Author: %s\n", post.Author)
+		}
+		categories, err := s.db.GetPostCategories(context.Background(), post.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get categories: %w", err)
+		}
+		if len(categories) > 0 {
+			fmt.Printf("Categories: %s\n", strings.Join(categories, ", "))
+		}
+		if post.CommentsUrl != "" {
+			fmt.Printf("Comments: %s\n", post.CommentsUrl)
+		}
+		enclosures, err := s.db.GetEnclosuresForPost(context.Background(), post.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get enclosures: %w", err)
+		}
+		for _, enclosure := range enclosures {
+			fmt.Printf("Enclosure: %s\n", formatEnclosure(enclosure))
+			if enclosure.Image.Valid {
+				fmt.Printf("Image: %s\n", enclosure.Image.String)
+			}
+		}
 		fmt.Println("--------------------------------------------------")
+		_, err = s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+			UserID: user.ID,
+			PostID: post.ID,
+			ReadAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark post read: %w", err)
+		}
+	}
+	return nil
+}
+
+func handlerRead(s *state, cmd command, user database.User) error {
+	if len(cmd.arg) < 1 {
+		return errors.New("enter post id")
+	}
+	postID, err := uuid.Parse(cmd.arg[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id: %w", err)
+	}
+	marked, err := s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: postID,
+		ReadAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark post read: %w", err)
+	}
+	if marked == 0 {
+		return fmt.Errorf("post %s not found", postID)
+	}
+	fmt.Printf("Marked as read: %s\n", postID)
+	return nil
+}
+
+func handlerMarkRead(s *state, cmd command, user database.User) error {
+	args, all := cutSwitch(cmd.arg, "all")
+	args, flags, err := parseFlags(args, "feed", "before")
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 || (!all && len(flags) == 0) {
+		return errors.New("enter --feed <feed_url>, --before <date> or --all")
+	}
+	params := database.MarkPostsReadParams{
+		ReadAt: time.Now().UTC(),
+		UserID: user.ID,
+	}
+	if feedURL, ok := flags["feed"]; ok {
+		feed, err := s.db.GetFeedByURL(context.Background(), feedURL)
+		if err != nil {
+			return fmt.Errorf("failed to get feed by url: %w", err)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if before, ok := flags["before"]; ok {
+		date, err := dateparse.Parse(before)
+		if err != nil {
+			return fmt.Errorf("invalid date: %w", err)
+		}
+		params.Before = sql.NullTime{Time: date.UTC(), Valid: true}
+	}
+	marked, err := s.db.MarkPostsRead(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("failed to mark posts read: %w", err)
+	}
+	fmt.Printf("Marked %d posts as read\n", marked)
+	return nil
+}
+
+func handlerStar(s *state, cmd command, user database.User) error {
+	if len(cmd.arg) < 1 {
+		return errors.New("enter post id and optionally a note")
+	}
+	postID, err := uuid.Parse(cmd.arg[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id: %w", err)
+	}
+	note := strings.Join(cmd.arg[1:], " ")
+	starred, err := s.db.StarPost(context.Background(), database.StarPostParams{
+		UserID:    user.ID,
+		PostID:    postID,
+		CreatedAt: time.Now().UTC(),
+		Note: sql.NullString{
+			String: note,
+			Valid:  note != "",
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to star post: %w", err)
+	}
+	if starred == 0 {
+		return fmt.Errorf("post %s not found", postID)
+	}
+	fmt.Printf("Starred: %s\n", postID)
+	return nil
+}
+
+func handlerUnstar(s *state, cmd command, user database.User) error {
+	if len(cmd.arg) < 1 {
+		return errors.New("enter post id to unstar")
+	}
+	postID, err := uuid.Parse(cmd.arg[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id: %w", err)
+	}
+	unstarred, err := s.db.UnstarPost(context.Background(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: postID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unstar post: %w", err)
+	}
+	if unstarred == 0 {
+		return fmt.Errorf("post %s is not starred", postID)
+	}
+	fmt.Printf("Unstarred: %s\n", postID)
+	return nil
+}
+
+func handlerStarred(s *state, cmd command, user database.User) error {
+	posts, err := s.db.GetSavedPostsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get starred posts: %w", err)
+	}
+	if len(posts) == 0 {
+		fmt.Println("No starred posts")
+		return nil
+	}
+	for _, post := range posts {
+		fmt.Println("--------------------------------------------------")
+		fmt.Printf("ID: %s\nTitle: %s\nURL: %s\nPublished At: %s\nFeed: %s\nStarred At: %s\n",
+			post.ID, post.Title, post.Url, formatPublished(s, post.PublishedAt), post.FeedName, formatTime(s, post.StarredAt))
+		if post.Note != "" {
+			fmt.Printf("Note: %s\n", post.Note)
+		}
+	}
+	return nil
+}
+
+func handlerSearch(s *state, cmd command, user database.User) error {
+	args, allFeeds := cutSwitch(cmd.arg, "all-feeds")
+	args, flags, err := parseFlags(args, "feed", "since", "until", "limit")
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New("enter search query")
+	}
+	params := database.SearchPostsParams{
+		Query:    strings.Join(args, " "),
+		AllFeeds: allFeeds,
+		UserID:   user.ID,
+		MaxPosts: 10,
+	}
+	if limit, ok := flags["limit"]; ok {
+		n, err := strconv.ParseInt(limit, 10, 32)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid limit: %s", limit)
+		}
+		params.MaxPosts = int32(n)
+	}
+	if feedURL, ok := flags["feed"]; ok {
+		feed, err := s.db.GetFeedByURL(context.Background(), feedURL)
+		if err != nil {
+			return fmt.Errorf("failed to get feed by url: %w", err)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if since, ok := flags["since"]; ok {
+		date, err := dateparse.Parse(since)
+		if err != nil {
+			return fmt.Errorf("invalid date: %w", err)
+		}
+		params.Since = sql.NullTime{Time: date.UTC(), Valid: true}
+	}
+	if until, ok := flags["until"]; ok {
+		date, err := dateparse.Parse(until)
+		if err != nil {
+			return fmt.Errorf("invalid date: %w", err)
+		}
+		params.Until = sql.NullTime{Time: date.UTC(), Valid: true}
+	}
+	posts, err := s.db.SearchPosts(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("failed to search posts: %w", err)
+	}
+	if len(posts) == 0 {
+		fmt.Println("No posts found")
+		return nil
+	}
+	for _, post := range posts {
+		fmt.Println("--------------------------------------------------")
+		fmt.Printf("ID: %s\nTitle: %s\nURL: %s\nPublished At: %s\nFeed: %s\n",
+			post.ID, post.Title, post.Url, formatPublished(s, post.PublishedAt), post.FeedName)
+		if post.Snippet != "" {
+			fmt.Printf("Match: %s\n", post.Snippet)
+		}
+	}
+	return nil
+}
+
+func handlerDownload(s *state, cmd command, user database.User) error {
+	var feedID uuid.NullUUID
+	if len(cmd.arg) > 0 {
+		feed, err := s.db.GetFeedByURL(context.Background(), cmd.arg[0])
+		if err != nil {
+			return fmt.Errorf("failed to get feed by url: %w", err)
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	dir, err := s.cfg.Download.DownloadDir()
+	if err != nil {
+		return err
+	}
+	candidates, err := s.db.GetDownloadCandidates(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get enclosures: %w", err)
+	}
+	retained := retainedEnclosures(candidates, s.cfg.Download.KeepLast())
+	retainedIDs := make(map[uuid.UUID]bool)
+	for _, enclosure := range retained {
+		retainedIDs[enclosure.ID] = true
+	}
+	if err := pruneDownloads(s, user, retainedIDs); err != nil {
+		return err
+	}
+
+	var queue []database.GetDownloadCandidatesRow
+	for _, enclosure := range retained {
+		if enclosure.UserID != user.ID || (feedID.Valid && enclosure.FeedID != feedID.UUID) {
+			continue
+		}
+		download, err := s.db.GetDownload(context.Background(), enclosure.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to get download: %w", err)
+		}
+		if err == nil && download.Status == downloadDone {
+			if downloadIntact(download) {
+				continue
+			}
+			fmt.Printf("Missing or changed, downloading again: %s\n", download.Path)
+		}
+		queue = append(queue, enclosure)
+	}
+	if len(queue) == 0 {
+		fmt.Println("Everything is downloaded")
+		return nil
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	jobs := make(chan database.GetDownloadCandidatesRow)
+	for range min(s.cfg.Download.DownloadConcurrency(), len(queue)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for enclosure := range jobs {
+				download, err := downloadEnclosure(s, enclosure, downloadPath(dir, enclosure))
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s - %s: %w", enclosure.FeedName, enclosure.PostTitle, err))
+				} else {
+					fmt.Printf("Downloaded: %s (%s)\n", download.Path, formatBytes(download.Bytes))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, enclosure := range queue {
+		jobs <- enclosure
+	}
+	close(jobs)
+	wg.Wait()
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d downloads failed", len(errs), len(queue))
+	}
+	return nil
+}
+
+func handlerKeepLast(s *state, cmd command, user database.User) error {
+	if len(cmd.arg) < 2 {
+		return errors.New("enter feed url and the number of episodes to keep (or all, or default)")
+	}
+	feed, err := s.db.GetFeedByURL(context.Background(), cmd.arg[0])
+	if err != nil {
+		return fmt.Errorf("failed to get feed by url: %w", err)
+	}
+	var keepLast sql.NullInt32
+	switch cmd.arg[1] {
+	case "default":
+	case "all":
+		keepLast = sql.NullInt32{Int32: -1, Valid: true}
+	default:
+		n, err := strconv.Atoi(cmd.arg[1])
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid number of episodes: %s", cmd.arg[1])
+		}
+		keepLast = sql.NullInt32{Int32: int32(n), Valid: true}
+	}
+	updated, err := s.db.SetFollowKeepLast(context.Background(), database.SetFollowKeepLastParams{
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+		KeepLast:  keepLast,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set episodes to keep: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("you do not follow %s", feed.Name)
 	}
+	fmt.Printf("Episodes to keep set for %s: %s\n", feed.Name, cmd.arg[1])
 	return nil
 }
 
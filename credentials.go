@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Corogura/gator/internal/database"
+	"github.com/Corogura/gator/internal/secret"
+	"github.com/google/uuid"
+)
+
+// Supported values of feed_credentials.auth_type.
+const (
+	authBasic  = "basic"
+	authBearer = "bearer"
+	authQuery  = "query"
+)
+
+// feedCredentials is the decrypted auth setting of a feed. Value is
+// "user:password" for basic, the token for bearer and "param=token" for
+// query auth.
+type feedCredentials struct {
+	Type  string
+	Value string
+}
+
+func (c feedCredentials) validate() error {
+	switch c.Type {
+	case authBasic:
+		if !strings.Contains(c.Value, ":") {
+			return errors.New("basic auth takes <user>:<password>")
+		}
+	case authBearer:
+		if c.Value == "" {
+			return errors.New("bearer auth takes a token")
+		}
+	case authQuery:
+		if param, token, ok := strings.Cut(c.Value, "="); !ok || param == "" || token == "" {
+			return errors.New("query auth takes <param>=<token>")
+		}
+	default:
+		return fmt.Errorf("unknown auth type %q, use basic, bearer or query", c.Type)
+	}
+	return nil
+}
+
+// apply adds the credentials to a request for the feed.
+func (c feedCredentials) apply(req *http.Request) {
+	switch c.Type {
+	case authBasic:
+		user, password, _ := strings.Cut(c.Value, ":")
+		req.SetBasicAuth(user, password)
+	case authBearer:
+		req.Header.Set("Authorization", "Bearer "+c.Value)
+	case authQuery:
+		param, token, _ := strings.Cut(c.Value, "=")
+		query := req.URL.Query()
+		query.Set(param, token)
+		req.URL.RawQuery = query.Encode()
+	}
+}
+
+// redactURL removes a query token from rawURL so it is not stored as the
+// feed URL or in an error message.
+func (c feedCredentials) redactURL(rawURL string) string {
+	if c.Type != authQuery {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	param, _, _ := strings.Cut(c.Value, "=")
+	query := u.Query()
+	if !query.Has(param) {
+		return rawURL
+	}
+	query.Del(param)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// redactError removes a query token from the URL of a request error.
+func (c feedCredentials) redactError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = c.redactURL(urlErr.URL)
+	}
+	return err
+}
+
+func credentialsKey(s *state) ([]byte, error) {
+	encoded := s.cfg.CredentialsKey()
+	if encoded == "" {
+		return nil, errors.New("no credentials key: set credentials_key in the config or GATOR_CREDENTIALS_KEY")
+	}
+	key, err := secret.ParseKey(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials key: %w", err)
+	}
+	return key, nil
+}
+
+// saveFeedCredentials encrypts creds and stores them for the feed. The
+// feed ID is authenticated with the ciphertext, so a stored secret cannot
+// be moved to another feed.
+func saveFeedCredentials(s *state, feedID uuid.UUID, creds feedCredentials) error {
+	if err := creds.validate(); err != nil {
+		return err
+	}
+	key, err := credentialsKey(s)
+	if err != nil {
+		return err
+	}
+	sealed, err := secret.Seal(key, []byte(creds.Value), feedID[:])
+	if err != nil {
+		return fmt.Errorf("failed to encrypt credentials: %w", err)
+	}
+	err = s.db.SetFeedCredentials(context.Background(), database.SetFeedCredentialsParams{
+		FeedID:    feedID,
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		AuthType:  creds.Type,
+		Secret:    sealed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save credentials: %w", err)
+	}
+	return nil
+}
+
+// loadFeedCredentials returns the decrypted credentials of the feed, or nil
+// if it has none.
+func loadFeedCredentials(ctx context.Context, s *state, feedID uuid.UUID) (*feedCredentials, error) {
+	stored, err := s.db.GetFeedCredentials(ctx, feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+	key, err := credentialsKey(s)
+	if err != nil {
+		return nil, err
+	}
+	value, err := secret.Open(key, stored.Secret, feedID[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credentials: %w", err)
+	}
+	return &feedCredentials{Type: stored.AuthType, Value: string(value)}, nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// feedLinkTypes are the <link rel="alternate"> types that point to a feed.
+var feedLinkTypes = []string{
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/feed+json",
+}
+
+// commonFeedPaths are tried on the site root when a page links no feed.
+var commonFeedPaths = []string{"/feed", "/rss", "/feed.xml", "/rss.xml", "/atom.xml", "/index.xml", "/feed.json"}
+
+type discoveredFeed struct {
+	URL   string
+	Title string
+}
+
+// getDocument downloads rawURL with the same limits as a feed fetch and
+// returns the body, its Content-Type and the URL it was served from.
+func getDocument(ctx context.Context, s *state, rawURL string) ([]byte, string, *url.URL, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	req.Header.Set("User-Agent", s.cfg.Http.UserAgent())
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if err := s.limiter.wait(ctx, req.URL.Hostname()); err != nil {
+		return nil, "", nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", nil, errors.New("failed to fetch " + rawURL + ": " + resp.Status)
+	}
+	body, err := decodedBody(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, "", nil, err
+	}
+	dat, err := readLimited(body, s.cfg.MaxFeedBytes())
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return dat, resp.Header.Get("Content-Type"), resp.Request.URL, nil
+}
+
+// isFeedDocument reports whether the first element of a document, after any
+// XML declaration, comments and doctype, is the root of an RSS, RSS 1.0 or
+// Atom feed.
+func isFeedDocument(contentType string, dat []byte) bool {
+	dat, err := toUTF8(dat, contentType)
+	if err != nil {
+		return false
+	}
+	decoder := newXMLDecoder(dat, false)
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			switch start.Name.Local {
+			case "rss", "RDF", "feed":
+				return true
+			}
+			return false
+		}
+	}
+}
+
+// isHTML reports whether a document is a web page rather than a feed. Feeds
+// are often served as text/html, so a document whose root element is that
+// of a feed is never taken for a page.
+func isHTML(contentType string, dat []byte) bool {
+	if isFeedDocument(contentType, dat) {
+		return false
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(dat)
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// feedLinks returns the feeds advertised by <link rel="alternate"> tags in
+// the head of an HTML page, resolved against pageURL or a <base> tag.
+func feedLinks(dat []byte, pageURL *url.URL) []discoveredFeed {
+	var feeds []discoveredFeed
+	base := pageURL
+	tokenizer := html.NewTokenizer(bytes.NewReader(dat))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return feeds
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		attrs := make(map[string]string)
+		for _, attr := range token.Attr {
+			attrs[attr.Key] = strings.TrimSpace(attr.Val)
+		}
+		switch token.Data {
+		case "body":
+			// Feed links belong in the head
+			return feeds
+		case "base":
+			if href, err := pageURL.Parse(attrs["href"]); err == nil && attrs["href"] != "" {
+				base = href
+			}
+		case "link":
+			rels := strings.Fields(strings.ToLower(attrs["rel"]))
+			mediaType, _, _ := mime.ParseMediaType(attrs["type"])
+			if !slices.Contains(rels, "alternate") || !slices.Contains(feedLinkTypes, mediaType) || attrs["href"] == "" {
+				continue
+			}
+			href, err := base.Parse(attrs["href"])
+			if err != nil {
+				continue
+			}
+			if !slices.ContainsFunc(feeds, func(f discoveredFeed) bool { return f.URL == href.String() }) {
+				feeds = append(feeds, discoveredFeed{URL: href.String(), Title: attrs["title"]})
+			}
+		}
+	}
+}
+
+// probeCommonPaths returns the common feed locations on the site of
+// pageURL that serve a parsable feed.
+func probeCommonPaths(s *state, pageURL *url.URL) []discoveredFeed {
+	var feeds []discoveredFeed
+	for _, path := range commonFeedPaths {
+		candidate := pageURL.ResolveReference(&url.URL{Path: path})
+		dat, contentType, _, err := getDocument(context.Background(), s, candidate.String())
+		if err != nil || isHTML(contentType, dat) {
+			continue
+		}
+		parsed, err := parseFeed(dat, contentType, 1)
+		if err != nil {
+			continue
+		}
+		feeds = append(feeds, discoveredFeed{URL: candidate.String(), Title: parsed.Title})
+	}
+	return feeds
+}
+
+// discoverFeeds returns the feeds for rawURL. A URL that cannot be fetched
+// or does not serve an HTML page is returned as is; for a page, the feeds it links to are
+// returned, or else the ones found at common paths of the site.
+func discoverFeeds(s *state, rawURL string) ([]discoveredFeed, error) {
+	dat, contentType, pageURL, err := getDocument(context.Background(), s, rawURL)
+	if err != nil {
+		// The site may only be down for now, and agg reports the feed's
+		// errors if it stays so
+		fmt.Printf("Could not fetch %s, using the URL as given: %v\n", rawURL, err)
+		return []discoveredFeed{{URL: rawURL}}, nil
+	}
+	if !isHTML(contentType, dat) {
+		return []discoveredFeed{{URL: rawURL}}, nil
+	}
+	feeds := feedLinks(dat, pageURL)
+	if len(feeds) == 0 {
+		feeds = probeCommonPaths(s, pageURL)
+	}
+	if len(feeds) == 0 {
+		return nil, fmt.Errorf("%s is a web page that links to no feed", rawURL)
+	}
+	return feeds, nil
+}
+
+// chooseFeed resolves rawURL to a feed URL, asking the user to pick one
+// when a page offers several.
+func chooseFeed(s *state, rawURL string) (string, error) {
+	feeds, err := discoverFeeds(s, rawURL)
+	if err != nil {
+		return "", err
+	}
+	if len(feeds) == 1 {
+		if feeds[0].URL != rawURL {
+			fmt.Printf("Found feed: %s\n", feeds[0].URL)
+		}
+		return feeds[0].URL, nil
+	}
+	fmt.Printf("Found %d feeds on %s:\n", len(feeds), rawURL)
+	options := make([]string, len(feeds))
+	for i, feed := range feeds {
+		options[i] = feed.URL
+		if feed.Title != "" {
+			options[i] = feed.Title + " - " + feed.URL
+		}
+	}
+	choice, err := chooseOption(options)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", errors.New("no feed chosen")
+		}
+		return "", err
+	}
+	return feeds[choice].URL, nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestIsHTML(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		doc         string
+		want        bool
+	}{
+		{"RSS served as HTML", "text/html", `<?xml version="1.0"?><rss version="2.0"><channel></channel></rss>`, false},
+		{"Atom after a comment and doctype", "text/html; charset=utf-8", `<?xml version="1.0"?><!-- generator --><!DOCTYPE feed><feed xmlns="http://www.w3.org/2005/Atom"></feed>`, false},
+		{"RSS 1.0", "text/html", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"></rdf:RDF>`, false},
+		{"legacy charset", "text/html", "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss><channel><title>Caf\xe9</title></channel></rss>", false},
+		{"page", "text/html", `<!DOCTYPE html><html><head><title>Blog</title></head></html>`, true},
+		{"page with a feed-like custom element", "text/html", `<!DOCTYPE html><html><body><feed-widget></feed-widget></body></html>`, true},
+		{"page with feed markup in a comment", "text/html", `<!-- <rss> --><html><head></head></html>`, true},
+		{"page without Content-Type", "", `<!DOCTYPE html><html><head></head><body><p>Hi</p></body></html>`, true},
+		{"XHTML page", "application/xhtml+xml", `<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml"></html>`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isHTML(tt.contentType, []byte(tt.doc)); got != tt.want {
+				t.Errorf("isHTML() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
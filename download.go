@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Corogura/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// downloadDone is the downloads.status of a completed download; the
+// others are "downloading", "failed" and "deleted".
+const downloadDone = "done"
+
+// mediaExts are the file extensions of audio and video files, which tell
+// apart the media among enclosures given without a MIME type.
+var mediaExts = map[string]bool{
+	".aac": true, ".flac": true, ".m4a": true, ".m4b": true, ".mp3": true,
+	".oga": true, ".ogg": true, ".opus": true, ".wav": true,
+	".avi": true, ".m4v": true, ".mkv": true, ".mov": true, ".mp4": true, ".webm": true,
+}
+
+// isMediaEnclosure reports whether an enclosure is audio or video. The
+// candidates only have audio or video MIME types, if any, so untyped ones
+// are judged by their URL.
+func isMediaEnclosure(enclosure database.GetDownloadCandidatesRow) bool {
+	if enclosure.MimeType.Valid {
+		return true
+	}
+	return mediaExts[strings.ToLower(enclosureExt(enclosure.Url, enclosure.MimeType))]
+}
+
+// retainedEnclosures picks the enclosures kept by each follower's keep-last
+// limit from candidates, which are grouped by follower, feed and post,
+// newest first. Each post is one episode, of which the first audio or video
+// enclosure is kept. Those of starred posts are always kept and do not
+// count toward the limit.
+func retainedEnclosures(candidates []database.GetDownloadCandidatesRow, defaultKeep int) []database.GetDownloadCandidatesRow {
+	type follow struct {
+		userID, feedID uuid.UUID
+	}
+	type episode struct {
+		userID, postID uuid.UUID
+	}
+	var retained []database.GetDownloadCandidatesRow
+	kept := make(map[follow]int)
+	seen := make(map[episode]bool)
+	for _, candidate := range candidates {
+		key := episode{candidate.UserID, candidate.PostID}
+		if seen[key] || !isMediaEnclosure(candidate) {
+			continue
+		}
+		seen[key] = true
+		if candidate.Starred {
+			retained = append(retained, candidate)
+			continue
+		}
+		keep := defaultKeep
+		if candidate.KeepLast.Valid {
+			keep = int(candidate.KeepLast.Int32)
+		}
+		feed := follow{candidate.UserID, candidate.FeedID}
+		if keep >= 0 && kept[feed] >= keep {
+			continue
+		}
+		kept[feed]++
+		retained = append(retained, candidate)
+	}
+	return retained
+}
+
+// sanitizeFilename makes name safe to use as a file name on common file
+// systems.
+func sanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	name = strings.Trim(name, " .")
+	if name == "" {
+		return "untitled"
+	}
+	return name
+}
+
+// enclosureExt returns the file extension of an enclosure, from its URL or
+// else its MIME type.
+func enclosureExt(rawURL string, mimeType sql.NullString) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		ext := path.Ext(u.Path)
+		if len(ext) > 1 && len(ext) <= 6 {
+			return ext
+		}
+	}
+	if mimeType.Valid {
+		if exts, err := mime.ExtensionsByType(mimeType.String); err == nil && len(exts) > 0 {
+			return exts[0]
+		}
+	}
+	return ""
+}
+
+// downloadPath is where an enclosure is saved: a directory per feed and a
+// file named after the post. The enclosure ID keeps posts with the same
+// title apart.
+func downloadPath(dir string, enclosure database.GetDownloadCandidatesRow) string {
+	name := fmt.Sprintf("%s [%s]%s", sanitizeFilename(enclosure.PostTitle), enclosure.ID.String()[:8], enclosureExt(enclosure.Url, enclosure.MimeType))
+	return filepath.Join(dir, sanitizeFilename(enclosure.FeedName), name)
+}
+
+func sha256File(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// downloadIntact reports whether a completed download is still on disk
+// with the recorded size and checksum.
+func downloadIntact(download database.Download) bool {
+	info, err := os.Stat(download.Path)
+	if err != nil || info.Size() != download.Bytes {
+		return false
+	}
+	sum, err := sha256File(download.Path)
+	return err == nil && sum == download.Sha256.String
+}
+
+// parseContentRange parses "bytes <start>-<end>/<total>" or
+// "bytes */<total>". start is -1 for the latter and total is -1 when
+// unknown.
+func parseContentRange(header string) (start, total int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rangeSpec, totalSpec, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+	total = -1
+	if totalSpec != "*" {
+		if _, err := fmt.Sscanf(totalSpec, "%d", &total); err != nil {
+			return 0, 0, false
+		}
+	}
+	if rangeSpec == "*" {
+		return -1, total, true
+	}
+	if _, err := fmt.Sscanf(rangeSpec, "%d-", &start); err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+// idleTimeoutReader cancels a download through its timer when no data
+// arrives for timeout. The client's overall timeout cannot be used for
+// files that take minutes to download.
+type idleTimeoutReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.timer.Reset(r.timeout)
+	return n, err
+}
+
+// responseValidator returns the validator of a response usable in If-Range:
+// a strong ETag, or else Last-Modified.
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// sameValidator reports whether a response does not contradict validator,
+// comparing the header it was taken from.
+func sameValidator(resp *http.Response, validator string) bool {
+	header := "Last-Modified"
+	if strings.HasPrefix(validator, `"`) {
+		header = "ETag"
+	}
+	current := resp.Header.Get(header)
+	return current == "" || current == validator
+}
+
+// fetchToFile downloads rawURL to name through a .part file, resuming a
+// previous partial download with a Range request. The part is only resumed
+// with the validator of the response it was started from, sent as
+// If-Range, so a file replaced on the server is downloaded anew rather
+// than appended to the old part. The size is checked against the one
+// announced by the server. It returns the size, the SHA-256 of the file
+// and the validator.
+func fetchToFile(s *state, rawURL, name, validator string) (int64, string, string, error) {
+	partName := name + ".part"
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return 0, "", "", err
+	}
+	var offset int64
+	if info, err := os.Stat(partName); err == nil {
+		offset = info.Size()
+	}
+	if offset > 0 && validator == "" {
+		// Nothing tells whether the part is of the current file
+		offset = 0
+	}
+
+	timeout, err := s.cfg.Http.TimeoutDuration()
+	if err != nil {
+		return 0, "", "", err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return 0, "", "", err
+	}
+	req.Header.Set("User-Agent", s.cfg.Http.UserAgent())
+	// Byte ranges must refer to the file itself, not a compressed form
+	req.Header.Set("Accept-Encoding", "identity")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	}
+	if err := s.limiter.wait(ctx, req.URL.Hostname()); err != nil {
+		return 0, "", "", err
+	}
+	client := *s.client
+	client.Timeout = 0
+	timer := time.AfterFunc(timeout, cancel)
+	defer timer.Stop()
+	resp, err := client.Do(req)
+	if err != nil {
+		return offset, "", validator, err
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The server ignored the range or the file changed, start over
+		offset = 0
+		flags |= os.O_TRUNC
+		total = resp.ContentLength
+		validator = responseValidator(resp)
+	case http.StatusPartialContent:
+		if !sameValidator(resp, validator) {
+			os.Remove(partName)
+			return 0, "", "", errors.New("file changed on the server, restarting next time")
+		}
+		var start int64
+		var ok bool
+		start, total, ok = parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			os.Remove(partName)
+			return 0, "", "", fmt.Errorf("unexpected Content-Range %q, restarting next time", resp.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The part file may already hold the whole file
+		var ok bool
+		_, total, ok = parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || total != offset {
+			os.Remove(partName)
+			return 0, "", "", errors.New("saved part does not match the file, restarting next time")
+		}
+	default:
+		return offset, "", validator, errors.New("failed to download: " + resp.Status)
+	}
+
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		f, err := os.OpenFile(partName, flags, 0o644)
+		if err != nil {
+			return offset, "", validator, err
+		}
+		n, err := io.Copy(f, idleTimeoutReader{r: resp.Body, timer: timer, timeout: timeout})
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		offset += n
+		if err != nil {
+			// The part file is kept so the next run resumes
+			return offset, "", validator, err
+		}
+	}
+	if total >= 0 && offset != total {
+		if offset > total {
+			os.Remove(partName)
+		}
+		return offset, "", validator, fmt.Errorf("size mismatch: got %d of %d bytes", offset, total)
+	}
+	sum, err := sha256File(partName)
+	if err != nil {
+		return offset, "", validator, err
+	}
+	if err := os.Rename(partName, name); err != nil {
+		return offset, "", validator, err
+	}
+	return offset, sum, validator, nil
+}
+
+// downloadEnclosure downloads an enclosure and records the outcome in the
+// downloads table. An enclosure keeps the path it was first given, so a
+// partial download can be resumed after its post is renamed.
+func downloadEnclosure(s *state, enclosure database.GetDownloadCandidatesRow, name string) (database.Download, error) {
+	download, err := s.db.StartDownload(context.Background(), database.StartDownloadParams{
+		ID:          uuid.New(),
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+		EnclosureID: enclosure.ID,
+		Path:        name,
+	})
+	if err != nil {
+		return database.Download{}, fmt.Errorf("failed to record download: %w", err)
+	}
+	size, sum, validator, fetchErr := fetchToFile(s, enclosure.Url, download.Path, download.Validator.String)
+	download.Validator = sql.NullString{String: validator, Valid: validator != ""}
+	if fetchErr != nil {
+		err = s.db.FailDownload(context.Background(), database.FailDownloadParams{
+			ID:        download.ID,
+			Bytes:     size,
+			Validator: download.Validator,
+			Error: sql.NullString{
+				String: fetchErr.Error(),
+				Valid:  true,
+			},
+			UpdatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return database.Download{}, fmt.Errorf("%w (failed to record error: %v)", fetchErr, err)
+		}
+		return database.Download{}, fetchErr
+	}
+	download.Status = downloadDone
+	download.Bytes = size
+	download.Sha256 = sql.NullString{String: sum, Valid: true}
+	download.CompletedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	err = s.db.FinishDownload(context.Background(), database.FinishDownloadParams{
+		ID:          download.ID,
+		Bytes:       download.Bytes,
+		Sha256:      download.Sha256,
+		Validator:   download.Validator,
+		CompletedAt: download.CompletedAt,
+	})
+	if err != nil {
+		return database.Download{}, fmt.Errorf("failed to record download: %w", err)
+	}
+	return download, nil
+}
+
+// pruneDownloads deletes the completed downloads of the user's feeds that
+// fell out of the keep-last limit of every follower. Downloads are shared
+// by all users, so retained must hold the enclosures kept by any of them.
+func pruneDownloads(s *state, user database.User, retained map[uuid.UUID]bool) error {
+	completed, err := s.db.GetCompletedDownloadsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get downloads: %w", err)
+	}
+	for _, download := range completed {
+		if retained[download.EnclosureID] {
+			continue
+		}
+		if err := os.Remove(download.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", download.Path, err)
+		}
+		err = s.db.MarkDownloadDeleted(context.Background(), database.MarkDownloadDeletedParams{
+			ID:        download.ID,
+			UpdatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record deleted download: %w", err)
+		}
+		fmt.Printf("Removed: %s\n", download.Path)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Corogura/gator/internal/config"
+)
+
+func newTestState(t *testing.T) *state {
+	t.Helper()
+	cfg := &config.Config{}
+	client, err := newHTTPClient(cfg.Http)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter, err := newHostLimiters(cfg.Http)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &state{cfg: cfg, client: client, limiter: limiter}
+}
+
+func TestFetchToFileResume(t *testing.T) {
+	const original = "0123456789"
+	const replaced = "abcdefghij"
+	content, etag := original, `"v1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "episode.mp3", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		part          string
+		validator     string
+		serverContent string
+		serverETag    string
+		want          string
+	}{
+		{"resumes the same file", original[:4], `"v1"`, original, `"v1"`, original},
+		{"restarts a file replaced on the server", original[:4], `"v1"`, replaced, `"v2"`, replaced},
+		{"restarts a part without validator", "xxxx", "", original, `"v1"`, original},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, etag = tt.serverContent, tt.serverETag
+			name := filepath.Join(t.TempDir(), "episode.mp3")
+			if err := os.WriteFile(name+".part", []byte(tt.part), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			size, _, validator, err := fetchToFile(newTestState(t), server.URL, name, tt.validator)
+			if err != nil {
+				t.Fatalf("fetchToFile failed: %v", err)
+			}
+			got, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want || size != int64(len(tt.want)) {
+				t.Errorf("file = %q (%d bytes), want %q", got, size, tt.want)
+			}
+			if validator != tt.serverETag {
+				t.Errorf("validator = %q, want %q", validator, tt.serverETag)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Corogura/gator/internal/database"
+)
+
+// RSSEnclosure is an RSS 2.0 <enclosure>. The attributes are kept as
+// strings because publishers often leave length empty or fill it with
+// junk, which would fail the whole item.
+type RSSEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+type ItunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// MediaContent is a Media RSS <media:content>, which may carry its own
+// thumbnails.
+type MediaContent struct {
+	URL       string           `xml:"url,attr"`
+	Type      string           `xml:"type,attr"`
+	FileSize  string           `xml:"fileSize,attr"`
+	Duration  string           `xml:"duration,attr"`
+	Thumbnail []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type MediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+// MediaGroup holds alternative renditions of the same media.
+type MediaGroup struct {
+	Content   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnail []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// ItemMedia collects the Media RSS and iTunes elements of an RSS item or
+// Atom entry.
+type ItemMedia struct {
+	ItunesDuration string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ItunesImage    ItunesImage      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	MediaContent   []MediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaGroup     []MediaGroup     `xml:"http://search.yahoo.com/mrss/ group"`
+	MediaThumbnail []MediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// decode reads a Media RSS or iTunes child element of an item.
+func (m *ItemMedia) decode(decoder *xml.Decoder, start xml.StartElement) error {
+	switch start.Name {
+	case xml.Name{Space: itunesNS, Local: "duration"}:
+		return decoder.DecodeElement(&m.ItunesDuration, &start)
+	case xml.Name{Space: itunesNS, Local: "image"}:
+		return decoder.DecodeElement(&m.ItunesImage, &start)
+	case xml.Name{Space: mediaNS, Local: "content"}:
+		var content MediaContent
+		err := decoder.DecodeElement(&content, &start)
+		m.MediaContent = append(m.MediaContent, content)
+		return err
+	case xml.Name{Space: mediaNS, Local: "group"}:
+		var group MediaGroup
+		err := decoder.DecodeElement(&group, &start)
+		m.MediaGroup = append(m.MediaGroup, group)
+		return err
+	case xml.Name{Space: mediaNS, Local: "thumbnail"}:
+		var thumbnail MediaThumbnail
+		err := decoder.DecodeElement(&thumbnail, &start)
+		m.MediaThumbnail = append(m.MediaThumbnail, thumbnail)
+		return err
+	}
+	return decoder.Skip()
+}
+
+// enclosures returns base followed by the media:content renditions of the
+// item, with the item's duration and image filled in where the enclosures
+// lack them.
+func (m ItemMedia) enclosures(base []FeedEnclosure) []FeedEnclosure {
+	image := strings.TrimSpace(m.ItunesImage.Href)
+	thumbnails := m.MediaThumbnail
+	contents := m.MediaContent
+	for _, group := range m.MediaGroup {
+		contents = append(contents, group.Content...)
+		thumbnails = append(thumbnails, group.Thumbnail...)
+	}
+	if image == "" && len(thumbnails) > 0 {
+		image = strings.TrimSpace(thumbnails[0].URL)
+	}
+
+	enclosures := base
+	for _, content := range contents {
+		enclosure := FeedEnclosure{
+			URL:      strings.TrimSpace(content.URL),
+			MimeType: strings.TrimSpace(content.Type),
+			Length:   parseLength(content.FileSize),
+			Duration: parseDuration(content.Duration),
+		}
+		if len(content.Thumbnail) > 0 {
+			enclosure.Image = strings.TrimSpace(content.Thumbnail[0].URL)
+		}
+		enclosures = append(enclosures, enclosure)
+	}
+	enclosures = mergeEnclosures(enclosures)
+	duration := parseDuration(m.ItunesDuration)
+	for i := range enclosures {
+		if enclosures[i].Duration == 0 {
+			enclosures[i].Duration = duration
+		}
+	}
+	return withImage(enclosures, image)
+}
+
+// mergeEnclosures drops enclosures without a URL and combines those with
+// the same URL, which feeds often give both as <enclosure> and
+// <media:content>.
+func mergeEnclosures(enclosures []FeedEnclosure) []FeedEnclosure {
+	var merged []FeedEnclosure
+	index := make(map[string]int)
+	for _, enclosure := range enclosures {
+		if enclosure.URL == "" {
+			continue
+		}
+		i, ok := index[enclosure.URL]
+		if !ok {
+			index[enclosure.URL] = len(merged)
+			merged = append(merged, enclosure)
+			continue
+		}
+		if merged[i].MimeType == "" {
+			merged[i].MimeType = enclosure.MimeType
+		}
+		if merged[i].Length == 0 {
+			merged[i].Length = enclosure.Length
+		}
+		if merged[i].Duration == 0 {
+			merged[i].Duration = enclosure.Duration
+		}
+		if merged[i].Image == "" {
+			merged[i].Image = enclosure.Image
+		}
+	}
+	return merged
+}
+
+// withImage sets image on the enclosures that have none, e.g. the
+// podcast's cover art on episodes without their own.
+func withImage(enclosures []FeedEnclosure, image string) []FeedEnclosure {
+	for i := range enclosures {
+		if enclosures[i].Image == "" {
+			enclosures[i].Image = image
+		}
+	}
+	return enclosures
+}
+
+func parseLength(length string) int64 {
+	n, err := strconv.ParseInt(strings.TrimSpace(length), 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// parseDuration parses a duration in seconds, as in media:content, or in
+// the [[HH:]MM:]SS form itunes:duration also allows. It returns 0 for
+// anything else.
+func parseDuration(duration string) int {
+	duration = strings.TrimSpace(duration)
+	if duration == "" {
+		return 0
+	}
+	seconds := 0.0
+	for _, part := range strings.Split(duration, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return int(seconds)
+}
+
+// formatEnclosure describes a stored enclosure on one line, e.g.
+// "https://example.com/ep1.mp3 (audio/mpeg, 24.1 MB, 1:02:03)".
+func formatEnclosure(enclosure database.Enclosure) string {
+	var details []string
+	if enclosure.MimeType.Valid {
+		details = append(details, enclosure.MimeType.String)
+	}
+	if enclosure.Length.Valid {
+		details = append(details, formatBytes(enclosure.Length.Int64))
+	}
+	if enclosure.Duration.Valid {
+		d := enclosure.Duration.Int32
+		if d >= 3600 {
+			details = append(details, fmt.Sprintf("%d:%02d:%02d", d/3600, d/60%60, d%60))
+		} else {
+			details = append(details, fmt.Sprintf("%d:%02d", d/60, d%60))
+		}
+	}
+	if len(details) == 0 {
+		return enclosure.Url
+	}
+	return fmt.Sprintf("%s (%s)", enclosure.Url, strings.Join(details, ", "))
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, prefix := float64(n)/unit, 0
+	for value >= unit && prefix < 3 {
+		value /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMGT"[prefix])
+}
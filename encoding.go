@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// acceptEncoding is sent instead of letting the transport add gzip on its
+// own, which also disables its transparent decompression.
+const acceptEncoding = "gzip, deflate, br"
+
+// decodedBody wraps body to undo the response's Content-Encoding.
+func decodedBody(body io.Reader, contentEncoding string) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		// "deflate" is meant to be zlib-wrapped, but some servers send a
+		// raw deflate stream. A zlib header starts with 0x78 for the usual
+		// 32K window.
+		buffered := bufio.NewReader(body)
+		head, err := buffered.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if head[0] == 0x78 {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+	case "br":
+		return brotli.NewReader(body), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding: %s", contentEncoding)
+}
+
+// readLimited reads all of r, failing once more than maxBytes are read.
+func readLimited(r io.Reader, maxBytes int64) ([]byte, error) {
+	dat, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(dat)) > maxBytes {
+		return nil, fmt.Errorf("feed is larger than %d bytes", maxBytes)
+	}
+	return dat, nil
+}
@@ -2,97 +2,490 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
-	"encoding/xml"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"html"
-	"io"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/Corogura/gator/internal/database"
+	"github.com/Corogura/gator/internal/dateparse"
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 )
 
-type RSSFeed struct {
-	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
-	} `xml:"channel"`
+type fetchResult struct {
+	Feed *ParsedFeed
+	// NotModified is set when the server answered 304 to a conditional
+	// request; Feed is nil in that case.
+	NotModified  bool
+	ETag         string
+	LastModified string
+	// CacheUntil is when the response expires per Cache-Control/Expires.
+	CacheUntil time.Time
+	// MovedTo is the new URL of a feed that was reached only through
+	// permanent (301/308) redirects.
+	MovedTo string
 }
 
-type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+// errFeedGone is returned by fetchFeed when the server answers 410 Gone.
+var errFeedGone = errors.New("feed is gone (410)")
+
+// fetchError is a failure to download or parse a feed, as opposed to a
+// failure to store it. Only these count toward disabling the feed.
+type fetchError struct {
+	err error
+}
+
+func (e *fetchError) Error() string {
+	return "failed to fetch feed: " + e.err.Error()
+}
+
+func (e *fetchError) Unwrap() error {
+	return e.err
+}
+
+// permanentRedirect returns the final URL of resp if it was reached through
+// one or more redirects that were all permanent.
+func permanentRedirect(resp *http.Response) (string, bool) {
+	redirected := false
+	for prev := resp.Request.Response; prev != nil; prev = prev.Request.Response {
+		if prev.StatusCode != http.StatusMovedPermanently && prev.StatusCode != http.StatusPermanentRedirect {
+			return "", false
+		}
+		redirected = true
+	}
+	if !redirected {
+		return "", false
+	}
+	return resp.Request.URL.String(), true
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+func fetchFeed(ctx context.Context, s *state, feed database.Feed) (*fetchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", feed.Url, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("User-Agent", s.cfg.Http.UserAgent())
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, application/json;q=0.8, */*;q=0.5")
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if feed.Etag.Valid {
+		req.Header.Set("If-None-Match", feed.Etag.String)
+	}
+	if feed.LastModified.Valid {
+		req.Header.Set("If-Modified-Since", feed.LastModified.String)
+	}
+	creds, err := loadFeedCredentials(ctx, s, feed.ID)
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		creds.apply(req)
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	if err := s.limiter.wait(ctx, req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
 	if err != nil {
+		if creds != nil {
+			err = creds.redactError(err)
+		}
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	result := &fetchResult{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		CacheUntil:   cacheExpiry(resp.Header, time.Now().UTC()),
+	}
+	if movedTo, ok := permanentRedirect(resp); ok {
+		if creds != nil {
+			movedTo = creds.redactURL(movedTo)
+		}
+		if movedTo != feed.Url {
+			result.MovedTo = movedTo
+		}
+	}
+	if resp.StatusCode == http.StatusGone {
+		return nil, errFeedGone
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if until, ok := retryAfter(resp.Header, time.Now().UTC()); ok {
+			throttled := &throttledError{
+				Host:     req.URL.Hostname(),
+				Status:   resp.Status,
+				Until:    until,
+				Location: s.location,
+			}
+			s.limiter.block(throttled)
+			return nil, throttled
+		}
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		// A 304 may omit the validators; keep the ones we sent
+		if result.ETag == "" {
+			result.ETag = feed.Etag.String
+		}
+		if result.LastModified == "" {
+			result.LastModified = feed.LastModified.String
+		}
+		return result, nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, errors.New("failed to fetch feed: " + resp.Status)
 	}
 
-	dat, err := io.ReadAll(resp.Body)
+	if resp.ContentLength > s.cfg.MaxFeedBytes() {
+		return nil, fmt.Errorf("feed is larger than %d bytes", s.cfg.MaxFeedBytes())
+	}
+	body, err := decodedBody(resp.Body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, err
+	}
+	// The limit applies to the decoded size, so a small compressed
+	// response cannot expand without bound. It is what bounds memory: the
+	// whole document is read before parsing, which needs it whole to detect
+	// the charset and to retry leniently, so the item limit only saves
+	// decoding work.
+	dat, err := readLimited(body, s.cfg.MaxFeedBytes())
 	if err != nil {
 		return nil, err
 	}
 
-	var feed RSSFeed
-	if err := xml.Unmarshal(dat, &feed); err != nil {
+	result.Feed, err = parseFeed(dat, resp.Header.Get("Content-Type"), s.cfg.MaxFeedItems())
+	if err != nil {
 		return nil, err
 	}
+	return result, nil
+}
 
-	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
-	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
-	for i, item := range feed.Channel.Item {
-		feed.Channel.Item[i].Title = html.UnescapeString(item.Title)
-		feed.Channel.Item[i].Description = html.UnescapeString(item.Description)
+// itemGUID identifies an item within its feed: the publisher's guid/id if
+// given, then the link, then a hash of the content for items that have
+// neither.
+func itemGUID(item FeedItem) string {
+	if item.GUID != "" {
+		return item.GUID
 	}
-	return &feed, nil
+	if item.Link != "" {
+		return item.Link
+	}
+	sum := sha256.Sum256([]byte(item.Title + "\n" + item.Description + "\n" + item.PubDate))
+	return "sha256:" + hex.EncodeToString(sum[:])
 }
 
-func scrapeFeeds(s *state) error {
-	feeds, err := s.db.GetNextFeedToFetch(context.Background())
+// scrapeFeeds claims up to concurrency of the feeds that are due and
+// fetches them in parallel. Claiming marks the feeds fetched, leases them
+// until the fetch is recorded and skips rows locked by another agg process,
+// so concurrent processes never share a feed.
+func scrapeFeeds(s *state, concurrency int) []error {
+	feeds, err := s.db.GetNextFeedsToFetch(context.Background(), database.GetNextFeedsToFetchParams{
+		LastFetchedAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+		UpdatedAt: time.Now().UTC(),
+		Limit:     int32(concurrency),
+		NextFetchAt: sql.NullTime{
+			Time:  time.Now().UTC().Add(claimLease),
+			Valid: true,
+		},
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get next feed to fetch: %w", err)
+		return []error{fmt.Errorf("failed to get next feeds to fetch: %w", err)}
 	}
-	feed, err := s.db.MarkFeedFetched(context.Background(), database.MarkFeedFetchedParams{
-		ID:        feeds.ID,
-		UpdatedAt: time.Now(),
-		LastFetchedAt: sql.NullTime{
-			Time:  time.Now(),
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, feed := range feeds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := scrapeFeed(s, &feed)
+			var (
+				throttled *throttledError
+				fetchErr  *fetchError
+			)
+			if err == nil {
+				err = recordFeedSuccess(s, feed, result)
+			} else if errors.Is(err, errFeedGone) {
+				err = markFeedGone(s, feed)
+			} else if errors.As(err, &throttled) {
+				err = deferFeedFetch(s, feed, throttled)
+			} else if errors.As(err, &fetchErr) {
+				err = recordFeedFailure(s, feed, err)
+			}
+			// Failing to store a fetched feed is reported without counting
+			// against the feed, which is fetched again once its claim
+			// lease expires.
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", feed.Url, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func recordFeedSuccess(s *state, feed database.Feed, result *fetchResult) error {
+	// A 304 carries no document, so the hints stored by the last parse
+	// apply
+	hints := storedHints(feed)
+	if result.Feed != nil {
+		hints = result.Feed.Refresh
+	}
+	postTimes, err := s.db.GetRecentPostTimes(context.Background(), database.GetRecentPostTimesParams{
+		FeedID: feed.ID,
+		Limit:  postHistorySize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get post history: %w", err)
+	}
+	minInterval, maxInterval, err := s.cfg.RefreshIntervals()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	interval := adaptiveInterval(now, postTimes, minInterval, maxInterval)
+	err = s.db.RecordFeedSuccess(context.Background(), database.RecordFeedSuccessParams{
+		ID: feed.ID,
+		NextFetchAt: sql.NullTime{
+			Time:  nextFetchAt(now, interval, hints, result.CacheUntil),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record successful fetch: %w", err)
+	}
+	return nil
+}
+
+// recordFeedFailure stores fetchErr on the feed, backs off exponentially
+// and disables the feed once it has failed too many times in a row. It
+// returns fetchErr for reporting.
+func recordFeedFailure(s *state, feed database.Feed, fetchErr error) error {
+	updated, err := s.db.RecordFeedFailure(context.Background(), database.RecordFeedFailureParams{
+		ID: feed.ID,
+		NextFetchAt: sql.NullTime{
+			Time:  time.Now().UTC().Add(failureBackoff(feed.ConsecutiveFailures + 1)),
+			Valid: true,
+		},
+		LastError: sql.NullString{
+			String: fetchErr.Error(),
+			Valid:  true,
+		},
+		LastErrorAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+		MaxFailures: int32(s.cfg.MaxFeedFailures()),
+	})
+	if err != nil {
+		return fmt.Errorf("%w (failed to record error: %v)", fetchErr, err)
+	}
+	if updated.DisabledAt.Valid {
+		return fmt.Errorf("%w (disabled after %d consecutive failures)", fetchErr, updated.ConsecutiveFailures)
+	}
+	return fetchErr
+}
+
+// deferFeedFetch postpones the feed until the host allows requests again.
+// Being throttled is not the feed's fault, so it does not count as a
+// failure. It returns throttled for reporting.
+func deferFeedFetch(s *state, feed database.Feed, throttled *throttledError) error {
+	err := s.db.DeferFeedFetch(context.Background(), database.DeferFeedFetchParams{
+		ID: feed.ID,
+		LastError: sql.NullString{
+			String: throttled.Error(),
+			Valid:  true,
+		},
+		LastErrorAt: sql.NullTime{
+			Time:  time.Now().UTC(),
+			Valid: true,
+		},
+		NextFetchAt: sql.NullTime{
+			Time:  throttled.Until,
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%w (failed to defer fetch: %v)", throttled, err)
+	}
+	return throttled
+}
+
+func markFeedGone(s *state, feed database.Feed) error {
+	err := s.db.MarkFeedGone(context.Background(), database.MarkFeedGoneParams{
+		ID: feed.ID,
+		GoneAt: sql.NullTime{
+			Time:  time.Now().UTC(),
 			Valid: true,
 		},
 	})
 	if err != nil {
-		return fmt.Errorf("failed to mark feed as fetched: %w", err)
+		return fmt.Errorf("failed to mark feed as gone: %w", err)
+	}
+	return errFeedGone
+}
+
+// moveFeed points feed at newURL after a permanent redirect. If another feed
+// already uses newURL, feed is merged into it: its follows and the posts the
+// other feed does not have yet are moved over and feed is deleted. It
+// returns the feed that now owns newURL.
+func moveFeed(s *state, feed database.Feed, newURL string) (database.Feed, error) {
+	tx, err := s.conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	defer tx.Rollback()
+	qtx := s.db.WithTx(tx)
+
+	target, err := qtx.GetFeedByURL(context.Background(), newURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		moved, err := qtx.UpdateFeedURL(context.Background(), database.UpdateFeedURLParams{
+			ID:        feed.ID,
+			Url:       newURL,
+			UpdatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return database.Feed{}, fmt.Errorf("failed to update feed url: %w", err)
+		}
+		return moved, tx.Commit()
+	}
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("failed to get feed by url: %w", err)
+	}
+
+	err = qtx.MoveFeedFollows(context.Background(), database.MoveFeedFollowsParams{
+		FromFeedID: feed.ID,
+		ToFeedID:   target.ID,
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("failed to move feed follows: %w", err)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	fetchedFeed, err := fetchFeed(ctx, feed.Url)
+	err = qtx.MovePosts(context.Background(), database.MovePostsParams{
+		FromFeedID: feed.ID,
+		ToFeedID:   target.ID,
+	})
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("failed to move posts: %w", err)
+	}
+	// The posts left behind are also in the target feed, and go with the
+	// merged feed. Their reads, stars and downloads go to the target's
+	// copies first.
+	err = qtx.MovePostReads(context.Background(), database.MovePostReadsParams{
+		FromFeedID: feed.ID,
+		ToFeedID:   target.ID,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to fetch feed: %w", err)
+		return database.Feed{}, fmt.Errorf("failed to move post reads: %w", err)
 	}
-	fmt.Printf("Fetched feed: %s\n", fetchedFeed.Channel.Title)
-	for _, item := range fetchedFeed.Channel.Item {
-		pubDate, err := parsePubDate(item.PubDate)
+	err = qtx.MoveSavedPosts(context.Background(), database.MoveSavedPostsParams{
+		FromFeedID: feed.ID,
+		ToFeedID:   target.ID,
+	})
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("failed to move starred posts: %w", err)
+	}
+	err = qtx.MoveDownloads(context.Background(), database.MoveDownloadsParams{
+		FromFeedID: feed.ID,
+		ToFeedID:   target.ID,
+	})
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("failed to move downloads: %w", err)
+	}
+	err = qtx.DeleteFeed(context.Background(), feed.ID)
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("failed to delete merged feed: %w", err)
+	}
+	return target, tx.Commit()
+}
+
+// uniqueCategories trims categories and drops empty and repeated ones,
+// ignoring case.
+func uniqueCategories(categories []string) []string {
+	// Not nil: a NULL array would keep the stored categories
+	unique := []string{}
+	seen := make(map[string]bool)
+	for _, category := range categories {
+		category = strings.TrimSpace(category)
+		key := strings.ToLower(category)
+		if category == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, category)
+	}
+	return unique
+}
+
+// saveEnclosures stores the enclosures of the post with the given guid.
+func saveEnclosures(s *state, feedID uuid.UUID, guid string, enclosures []FeedEnclosure) error {
+	for _, enclosure := range enclosures {
+		err := s.db.UpsertEnclosure(context.Background(), database.UpsertEnclosureParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			FeedID:    feedID,
+			Guid:      guid,
+			Url:       enclosure.URL,
+			MimeType: sql.NullString{
+				String: enclosure.MimeType,
+				Valid:  enclosure.MimeType != "",
+			},
+			Length: sql.NullInt64{
+				Int64: enclosure.Length,
+				Valid: enclosure.Length > 0,
+			},
+			Duration: sql.NullInt32{
+				Int32: int32(enclosure.Duration),
+				Valid: enclosure.Duration > 0,
+			},
+			Image: sql.NullString{
+				String: enclosure.Image,
+				Valid:  enclosure.Image != "",
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save enclosure: %w", err)
+		}
+	}
+	return nil
+}
+
+// scrapeFeed fetches feed and stores its posts. If the feed has permanently
+// moved, *feed is updated to the feed that now owns the new URL.
+func scrapeFeed(s *state, feed *database.Feed) (*fetchResult, error) {
+	result, err := fetchFeed(context.Background(), s, *feed)
+	if err != nil {
+		return nil, &fetchError{err: err}
+	}
+	if result.MovedTo != "" {
+		oldURL := feed.Url
+		*feed, err = moveFeed(s, *feed, result.MovedTo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to move feed to %s: %w", result.MovedTo, err)
+		}
+		fmt.Printf("Feed moved permanently: %s -> %s\n", oldURL, feed.Url)
+	}
+	if result.NotModified {
+		fmt.Printf("Feed not modified: %s\n", feed.Name)
+		return result, nil
+	}
+	fetchedFeed := result.Feed
+	fmt.Printf("Fetched feed: %s\n", fetchedFeed.Title)
+	for _, item := range fetchedFeed.Items {
+		pubDate, err := dateparse.Parse(item.PubDate)
 		var parsed sql.NullTime
 		if err != nil {
 			parsed = sql.NullTime{
@@ -101,27 +494,80 @@ func scrapeFeeds(s *state) error {
 			}
 		} else {
 			parsed = sql.NullTime{
-				Time:  pubDate,
+				Time:  pubDate.UTC(),
 				Valid: true,
 			}
 		}
-		_, err = s.db.CreatePost(context.Background(), database.CreatePostParams{
+		guid := itemGUID(item)
+		if guid != item.Link && item.Link != "" {
+			err = s.db.AdoptLegacyPost(context.Background(), database.AdoptLegacyPostParams{
+				Guid:   guid,
+				FeedID: feed.ID,
+				Url:    item.Link,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to adopt legacy post: %w", err)
+			}
+		}
+		_, err = s.db.UpsertPost(context.Background(), database.UpsertPostParams{
 			ID:          uuid.New(),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+			CreatedAt:   time.Now().UTC(),
+			UpdatedAt:   time.Now().UTC(),
 			FeedID:      feed.ID,
 			Title:       item.Title,
 			Url:         item.Link,
 			Description: item.Description,
 			PublishedAt: parsed,
+			Guid:        guid,
+			Content:     item.Content,
+			Author:      item.Author,
+			CommentsUrl: item.CommentsURL,
 		})
 		if err != nil {
-			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-				continue
-			} else {
-				return fmt.Errorf("failed to create post: %w", err)
-			}
+			return nil, fmt.Errorf("failed to save post: %w", err)
+		}
+		// The upsert changes no row when the stored post is unchanged, but
+		// its categories and enclosures may still have changed.
+		err = s.db.SetPostCategories(context.Background(), database.SetPostCategoriesParams{
+			FeedID: feed.ID,
+			Guid:   guid,
+			Names:  uniqueCategories(item.Categories),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save categories: %w", err)
+		}
+		if err := saveEnclosures(s, feed.ID, guid, item.Enclosures); err != nil {
+			return nil, err
 		}
 	}
-	return nil
+	// Only remember the validators once every item is stored, so a failed
+	// run downloads the document again instead of getting a 304.
+	err = s.db.SetFeedCacheValidators(context.Background(), database.SetFeedCacheValidatorsParams{
+		ID: feed.ID,
+		Etag: sql.NullString{
+			String: result.ETag,
+			Valid:  result.ETag != "",
+		},
+		LastModified: sql.NullString{
+			String: result.LastModified,
+			Valid:  result.LastModified != "",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save cache validators: %w", err)
+	}
+	err = s.db.SetFeedParseWarning(context.Background(), database.SetFeedParseWarningParams{
+		ID: feed.ID,
+		ParseWarning: sql.NullString{
+			String: fetchedFeed.Warning,
+			Valid:  fetchedFeed.Warning != "",
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save parse warning: %w", err)
+	}
+	if err := saveHints(s, feed.ID, fetchedFeed.Refresh); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
@@ -3,6 +3,11 @@ module github.com/Corogura/gator
 go 1.24.3
 
 require (
+	github.com/andybalholm/brotli v1.2.0
 	github.com/google/uuid v1.6.0
 	github.com/lib/pq v1.10.9
+	golang.org/x/net v0.50.0
+	golang.org/x/time v0.14.0
 )
+
+require golang.org/x/text v0.34.0 // indirect
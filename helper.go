@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/url"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -42,21 +47,100 @@ func middlewareLoggedIn(handler func(s *state, cmd command, user database.User)
 	}
 }
 
-func parsePubDate(pubDate string) (time.Time, error) {
-	// Parse the publication date from the RSS item
-	pubTime, err := time.Parse(time.RFC1123, pubDate)
-	if err == nil {
-		return pubTime, nil
+const displayTimeLayout = "2006-01-02 15:04:05 MST"
+
+// formatTime prints t in the configured display time zone.
+func formatTime(s *state, t time.Time) string {
+	return t.In(s.location).Format(displayTimeLayout)
+}
+
+func formatPublished(s *state, publishedAt sql.NullTime) string {
+	if !publishedAt.Valid {
+		return "unknown"
+	}
+	return formatTime(s, publishedAt.Time)
+}
+
+func joinNonEmpty(values []string, sep string) string {
+	var parts []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// parseFlags separates "--name value" and "--name=value" options from the
+// positional arguments. Only the given names are accepted.
+func parseFlags(args []string, names ...string) ([]string, map[string]string, error) {
+	var positional []string
+	flags := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		name, ok := strings.CutPrefix(args[i], "--")
+		if !ok {
+			positional = append(positional, args[i])
+			continue
+		}
+		name, value, hasValue := strings.Cut(name, "=")
+		if !slices.Contains(names, name) {
+			return nil, nil, fmt.Errorf("unknown option --%s", name)
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("option --%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		flags[name] = value
 	}
-	// If RFC1123 fails, try RFC3339
-	pubTime, err = time.Parse(time.RFC3339, pubDate)
-	if err == nil {
-		return pubTime, nil
+	return positional, flags, nil
+}
+
+// cutSwitch removes the "--name" switch, which takes no value, from args
+// and reports whether it was given.
+func cutSwitch(args []string, name string) ([]string, bool) {
+	var rest []string
+	found := false
+	for _, arg := range args {
+		if arg == "--"+name {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
+
+// stdin is shared by every prompt, so input read ahead by one prompt, as
+// from a pipe, is not lost to the next.
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLine prompts on stdout and reads one line from stdin.
+func readLine(prompt string) (string, error) {
+	fmt.Print(prompt)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
 	}
-	// If both formats fail, try RFC822
-	pubTime, err = time.Parse(time.RFC822, pubDate)
-	if err == nil {
-		return pubTime, nil
+	return strings.TrimSpace(line), nil
+}
+
+// chooseOption lists options numbered from 1 and returns the index of the
+// one the user picks.
+func chooseOption(options []string) (int, error) {
+	for i, option := range options {
+		fmt.Printf("  %d) %s\n", i+1, option)
+	}
+	for {
+		line, err := readLine(fmt.Sprintf("Choose 1-%d: ", len(options)))
+		if err != nil {
+			return 0, err
+		}
+		choice, err := strconv.Atoi(line)
+		if err == nil && choice >= 1 && choice <= len(options) {
+			return choice - 1, nil
+		}
 	}
-	return time.Time{}, fmt.Errorf("failed to parse publication date: %w", err)
 }
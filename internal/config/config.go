@@ -2,14 +2,94 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 const configFileName = ".gatorconfig.json"
 
+// credentialsKeyEnv overrides credentials_key so the key can be kept out of
+// the config file.
+const credentialsKeyEnv = "GATOR_CREDENTIALS_KEY"
+
+const (
+	// DefaultMaxFeedFailures is used when max_feed_failures is not set.
+	DefaultMaxFeedFailures = 10
+	// DefaultMinRefreshInterval and DefaultMaxRefreshInterval bound the
+	// adaptive refresh interval when the config does not.
+	DefaultMinRefreshInterval = 15 * time.Minute
+	DefaultMaxRefreshInterval = 24 * time.Hour
+	// DefaultMaxFeedBytes and DefaultMaxFeedItems limit how much of a feed
+	// is downloaded and parsed when the config does not.
+	DefaultMaxFeedBytes = 10 << 20
+	DefaultMaxFeedItems = 500
+	// Defaults for the http section.
+	DefaultHTTPTimeout  = 10 * time.Second
+	DefaultUserAgent    = "gator"
+	DefaultMaxRedirects = 10
+	// DefaultHostInterval and DefaultHostBurst limit requests to each host
+	// when host_rate_limit is not set.
+	DefaultHostInterval = time.Second
+	DefaultHostBurst    = 2
+	// Defaults for the download section.
+	DefaultDownloadDir         = "gator-downloads"
+	DefaultDownloadConcurrency = 2
+	DefaultKeepLast            = 5
+)
+
 type Config struct {
-	Db_url            string `json:"db_url"`
-	Current_user_name string `json:"current_user_name"`
+	Db_url               string     `json:"db_url"`
+	Current_user_name    string     `json:"current_user_name"`
+	Max_feed_failures    int        `json:"max_feed_failures,omitempty"`
+	Min_refresh_interval string     `json:"min_refresh_interval,omitempty"`
+	Max_refresh_interval string     `json:"max_refresh_interval,omitempty"`
+	Max_feed_bytes       int64      `json:"max_feed_bytes,omitempty"`
+	Max_feed_items       int        `json:"max_feed_items,omitempty"`
+	Http                 HTTPConfig `json:"http,omitzero"`
+	Credentials_key      string     `json:"credentials_key,omitempty"`
+	Download             Download   `json:"download,omitzero"`
+	// Display_timezone is the IANA time zone, e.g. "Europe/Paris", in
+	// which times are printed. The local time zone is used when unset.
+	Display_timezone string `json:"display_timezone,omitempty"`
+}
+
+// Download configures the download command.
+type Download struct {
+	// Dir holds a subdirectory per feed. A relative path is relative to the
+	// home directory.
+	Dir         string `json:"dir,omitempty"`
+	Concurrency int    `json:"concurrency,omitempty"`
+	// Keep_last is the number of most recent episodes kept per feed unless
+	// a follow overrides it. A negative value keeps everything.
+	Keep_last int `json:"keep_last,omitempty"`
+}
+
+// HTTPConfig configures the HTTP client used to fetch feeds.
+type HTTPConfig struct {
+	// Timeout bounds a whole request including reading the body, e.g. "30s".
+	Timeout string `json:"timeout,omitempty"`
+	// Proxy is an HTTP(S) proxy URL. Without it the HTTP_PROXY, HTTPS_PROXY
+	// and NO_PROXY environment variables are used.
+	Proxy string `json:"proxy,omitempty"`
+	// Ca_file is a PEM bundle trusted in addition to the system roots.
+	Ca_file       string `json:"ca_file,omitempty"`
+	User_agent    string `json:"user_agent,omitempty"`
+	Max_redirects int    `json:"max_redirects,omitempty"`
+	// Host_rate_limit applies to every host without an entry in
+	// Host_rate_limits. Keys of Host_rate_limits are host names and also
+	// match their subdomains.
+	Host_rate_limit  RateLimit            `json:"host_rate_limit,omitzero"`
+	Host_rate_limits map[string]RateLimit `json:"host_rate_limits,omitempty"`
+}
+
+// RateLimit is a token bucket: one request per Interval on average, with
+// up to Burst requests at once.
+type RateLimit struct {
+	Interval string `json:"interval,omitempty"`
+	Burst    int    `json:"burst,omitempty"`
 }
 
 func getConfigPath() (string, error) {
@@ -52,3 +132,158 @@ func (c *Config) SetUser(username string) error {
 	os.WriteFile(dir, jsonData, 0666)
 	return nil
 }
+
+// MaxFeedFailures is the number of consecutive fetch failures after which
+// agg disables a feed. A negative value never disables feeds.
+func (c *Config) MaxFeedFailures() int {
+	if c.Max_feed_failures == 0 {
+		return DefaultMaxFeedFailures
+	}
+	return c.Max_feed_failures
+}
+
+// MaxFeedBytes is the largest feed document, after decompression, that is
+// downloaded. Documents are read whole before parsing, so this is the bound
+// on the memory a fetch uses.
+func (c *Config) MaxFeedBytes() int64 {
+	if c.Max_feed_bytes <= 0 {
+		return DefaultMaxFeedBytes
+	}
+	return c.Max_feed_bytes
+}
+
+// MaxFeedItems is the number of items read from a feed document; the rest
+// are not parsed. It does not bound memory, see MaxFeedBytes.
+func (c *Config) MaxFeedItems() int {
+	if c.Max_feed_items <= 0 {
+		return DefaultMaxFeedItems
+	}
+	return c.Max_feed_items
+}
+
+// RefreshIntervals returns the bounds of the adaptive refresh interval,
+// parsed from durations such as "10m" or "12h".
+func (c *Config) RefreshIntervals() (time.Duration, time.Duration, error) {
+	minInterval, maxInterval := DefaultMinRefreshInterval, DefaultMaxRefreshInterval
+	var err error
+	if c.Min_refresh_interval != "" {
+		minInterval, err = time.ParseDuration(c.Min_refresh_interval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid min_refresh_interval: %w", err)
+		}
+	}
+	if c.Max_refresh_interval != "" {
+		maxInterval, err = time.ParseDuration(c.Max_refresh_interval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid max_refresh_interval: %w", err)
+		}
+	}
+	if minInterval <= 0 || maxInterval < minInterval {
+		return 0, 0, fmt.Errorf("refresh intervals must satisfy 0 < min <= max, got %s and %s", minInterval, maxInterval)
+	}
+	return minInterval, maxInterval, nil
+}
+
+// CredentialsKey is the base64-encoded key that encrypts feed credentials,
+// taken from the GATOR_CREDENTIALS_KEY environment variable or else from
+// credentials_key. It is empty when neither is set.
+func (c *Config) CredentialsKey() string {
+	if key := os.Getenv(credentialsKeyEnv); key != "" {
+		return key
+	}
+	return c.Credentials_key
+}
+
+// DisplayLocation is the time zone times are printed in.
+func (c *Config) DisplayLocation() (*time.Location, error) {
+	if c.Display_timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Display_timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid display_timezone: %w", err)
+	}
+	return loc, nil
+}
+
+func (h HTTPConfig) TimeoutDuration() (time.Duration, error) {
+	if h.Timeout == "" {
+		return DefaultHTTPTimeout, nil
+	}
+	timeout, err := time.ParseDuration(h.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid http timeout: %w", err)
+	}
+	return timeout, nil
+}
+
+func (h HTTPConfig) UserAgent() string {
+	if h.User_agent == "" {
+		return DefaultUserAgent
+	}
+	return h.User_agent
+}
+
+func (h HTTPConfig) MaxRedirects() int {
+	if h.Max_redirects <= 0 {
+		return DefaultMaxRedirects
+	}
+	return h.Max_redirects
+}
+
+// HostRateLimit returns the interval and burst of the rate limit for host,
+// from the longest matching entry of host_rate_limits or else
+// host_rate_limit.
+func (h HTTPConfig) HostRateLimit(host string) (time.Duration, int, error) {
+	limit := h.Host_rate_limit
+	for domain := strings.ToLower(host); domain != ""; {
+		if l, ok := h.Host_rate_limits[domain]; ok {
+			limit = l
+			break
+		}
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+	interval := DefaultHostInterval
+	if limit.Interval != "" {
+		var err error
+		interval, err = time.ParseDuration(limit.Interval)
+		if err != nil || interval < 0 {
+			return 0, 0, fmt.Errorf("invalid rate limit interval %q for %s", limit.Interval, host)
+		}
+	}
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = DefaultHostBurst
+	}
+	return interval, burst, nil
+}
+
+// DownloadDir returns the absolute path of the download directory.
+func (d Download) DownloadDir() (string, error) {
+	dir := d.Dir
+	if dir == "" {
+		dir = DefaultDownloadDir
+	}
+	if filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, dir), nil
+}
+
+func (d Download) DownloadConcurrency() int {
+	if d.Concurrency <= 0 {
+		return DefaultDownloadConcurrency
+	}
+	return d.Concurrency
+}
+
+func (d Download) KeepLast() int {
+	if d.Keep_last == 0 {
+		return DefaultKeepLast
+	}
+	return d.Keep_last
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeed = `-- name: CreateFeed :one
@@ -23,7 +24,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, last_error_at, consecutive_failures, disabled_at, next_fetch_at, gone_at, parse_warning, refresh_interval_seconds, skip_hours, skip_days
 `
 
 type CreateFeedParams struct {
@@ -53,12 +54,79 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+		&i.NextFetchAt,
+		&i.GoneAt,
+		&i.ParseWarning,
+		&i.RefreshIntervalSeconds,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
 	)
 	return i, err
 }
 
+const deferFeedFetch = `-- name: DeferFeedFetch :exec
+UPDATE feeds
+SET last_error = $1,
+    last_error_at = $2,
+    next_fetch_at = $3
+WHERE id = $4
+`
+
+type DeferFeedFetchParams struct {
+	LastError   sql.NullString
+	LastErrorAt sql.NullTime
+	NextFetchAt sql.NullTime
+	ID          uuid.UUID
+}
+
+func (q *Queries) DeferFeedFetch(ctx context.Context, arg DeferFeedFetchParams) error {
+	_, err := q.db.ExecContext(ctx, deferFeedFetch,
+		arg.LastError,
+		arg.LastErrorAt,
+		arg.NextFetchAt,
+		arg.ID,
+	)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
+const enableFeed = `-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled_at = NULL,
+    gone_at = NULL,
+    consecutive_failures = 0,
+    next_fetch_at = NULL,
+    updated_at = $1
+WHERE id = $2
+`
+
+type EnableFeedParams struct {
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) EnableFeed(ctx context.Context, arg EnableFeedParams) error {
+	_, err := q.db.ExecContext(ctx, enableFeed, arg.UpdatedAt, arg.ID)
+	return err
+}
+
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, last_error_at, consecutive_failures, disabled_at, next_fetch_at, gone_at, parse_warning, refresh_interval_seconds, skip_hours, skip_days FROM feeds
 WHERE url = $1
 `
 
@@ -73,12 +141,24 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+		&i.NextFetchAt,
+		&i.GoneAt,
+		&i.ParseWarning,
+		&i.RefreshIntervalSeconds,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
 	)
 	return i, err
 }
 
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, last_error_at, consecutive_failures, disabled_at, next_fetch_at, gone_at, parse_warning, refresh_interval_seconds, skip_hours, skip_days FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -98,6 +178,18 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.ConsecutiveFailures,
+			&i.DisabledAt,
+			&i.NextFetchAt,
+			&i.GoneAt,
+			&i.ParseWarning,
+			&i.RefreshIntervalSeconds,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
 		); err != nil {
 			return nil, err
 		}
@@ -112,14 +204,124 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1
+const getNextFeedsToFetch = `-- name: GetNextFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at = $1,
+    updated_at = $2,
+    next_fetch_at = $4
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE disabled_at IS NULL
+        AND (next_fetch_at IS NULL OR next_fetch_at <= $1)
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, last_error_at, consecutive_failures, disabled_at, next_fetch_at, gone_at, parse_warning, refresh_interval_seconds, skip_hours, skip_days
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+type GetNextFeedsToFetchParams struct {
+	LastFetchedAt sql.NullTime
+	UpdatedAt     time.Time
+	Limit         int32
+	NextFetchAt   sql.NullTime
+}
+
+func (q *Queries) GetNextFeedsToFetch(ctx context.Context, arg GetNextFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getNextFeedsToFetch,
+		arg.LastFetchedAt,
+		arg.UpdatedAt,
+		arg.Limit,
+		arg.NextFetchAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.ConsecutiveFailures,
+			&i.DisabledAt,
+			&i.NextFetchAt,
+			&i.GoneAt,
+			&i.ParseWarning,
+			&i.RefreshIntervalSeconds,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedGone = `-- name: MarkFeedGone :exec
+UPDATE feeds
+SET gone_at = $1,
+    disabled_at = $1
+WHERE id = $2
+`
+
+type MarkFeedGoneParams struct {
+	GoneAt sql.NullTime
+	ID     uuid.UUID
+}
+
+func (q *Queries) MarkFeedGone(ctx context.Context, arg MarkFeedGoneParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedGone, arg.GoneAt, arg.ID)
+	return err
+}
+
+const recordFeedFailure = `-- name: RecordFeedFailure :one
+UPDATE feeds
+SET last_error = $1,
+    last_error_at = $2,
+    consecutive_failures = consecutive_failures + 1,
+    next_fetch_at = $3,
+    disabled_at = CASE
+        WHEN $4::int > 0 AND consecutive_failures + 1 >= $4::int THEN $2
+        ELSE disabled_at
+    END
+WHERE id = $5
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, last_error_at, consecutive_failures, disabled_at, next_fetch_at, gone_at, parse_warning, refresh_interval_seconds, skip_hours, skip_days
+`
+
+type RecordFeedFailureParams struct {
+	LastError   sql.NullString
+	LastErrorAt sql.NullTime
+	NextFetchAt sql.NullTime
+	MaxFailures int32
+	ID          uuid.UUID
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFailure,
+		arg.LastError,
+		arg.LastErrorAt,
+		arg.NextFetchAt,
+		arg.MaxFailures,
+		arg.ID,
+	)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -129,26 +331,116 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+		&i.NextFetchAt,
+		&i.GoneAt,
+		&i.ParseWarning,
+		&i.RefreshIntervalSeconds,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
 	)
 	return i, err
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :one
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
 UPDATE feeds
-SET last_fetched_at = $1,
+SET consecutive_failures = 0,
+    last_error = NULL,
+    last_error_at = NULL,
+    next_fetch_at = $1
+WHERE id = $2
+`
+
+type RecordFeedSuccessParams struct {
+	NextFetchAt sql.NullTime
+	ID          uuid.UUID
+}
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, arg.NextFetchAt, arg.ID)
+	return err
+}
+
+const setFeedCacheValidators = `-- name: SetFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $1,
+    last_modified = $2
+WHERE id = $3
+`
+
+type SetFeedCacheValidatorsParams struct {
+	Etag         sql.NullString
+	LastModified sql.NullString
+	ID           uuid.UUID
+}
+
+func (q *Queries) SetFeedCacheValidators(ctx context.Context, arg SetFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCacheValidators, arg.Etag, arg.LastModified, arg.ID)
+	return err
+}
+
+const setFeedParseWarning = `-- name: SetFeedParseWarning :exec
+UPDATE feeds
+SET parse_warning = $1
+WHERE id = $2
+`
+
+type SetFeedParseWarningParams struct {
+	ParseWarning sql.NullString
+	ID           uuid.UUID
+}
+
+func (q *Queries) SetFeedParseWarning(ctx context.Context, arg SetFeedParseWarningParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedParseWarning, arg.ParseWarning, arg.ID)
+	return err
+}
+
+const setFeedRefreshHints = `-- name: SetFeedRefreshHints :exec
+UPDATE feeds
+SET refresh_interval_seconds = $1,
+    skip_hours = $2,
+    skip_days = $3
+WHERE id = $4
+`
+
+type SetFeedRefreshHintsParams struct {
+	RefreshIntervalSeconds int32
+	SkipHours              []int32
+	SkipDays               []int32
+	ID                     uuid.UUID
+}
+
+func (q *Queries) SetFeedRefreshHints(ctx context.Context, arg SetFeedRefreshHintsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRefreshHints,
+		arg.RefreshIntervalSeconds,
+		pq.Array(arg.SkipHours),
+		pq.Array(arg.SkipDays),
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :one
+UPDATE feeds
+SET url = $1,
     updated_at = $2
 WHERE id = $3
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_error, last_error_at, consecutive_failures, disabled_at, next_fetch_at, gone_at, parse_warning, refresh_interval_seconds, skip_hours, skip_days
 `
 
-type MarkFeedFetchedParams struct {
-	LastFetchedAt sql.NullTime
-	UpdatedAt     time.Time
-	ID            uuid.UUID
+type UpdateFeedURLParams struct {
+	Url       string
+	UpdatedAt time.Time
+	ID        uuid.UUID
 }
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, markFeedFetched, arg.LastFetchedAt, arg.UpdatedAt, arg.ID)
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedURL, arg.Url, arg.UpdatedAt, arg.ID)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -158,6 +450,18 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.DisabledAt,
+		&i.NextFetchAt,
+		&i.GoneAt,
+		&i.ParseWarning,
+		&i.RefreshIntervalSeconds,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
 	)
 	return i, err
 }
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,10 +23,10 @@ WITH inserted_feed_follow AS (
         $4,
         $5
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, keep_last
 )
 SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.keep_last,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow
@@ -47,6 +48,7 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	KeepLast  sql.NullInt32
 	FeedName  string
 	UserName  string
 }
@@ -66,6 +68,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.KeepLast,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -74,7 +77,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 
 const getFeedFollowForUser = `-- name: GetFeedFollowForUser :many
 SELECT
-    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
+    feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.keep_last,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM feed_follows
@@ -89,6 +92,7 @@ type GetFeedFollowForUserRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	KeepLast  sql.NullInt32
 	FeedName  string
 	UserName  string
 }
@@ -108,6 +112,7 @@ func (q *Queries) GetFeedFollowForUser(ctx context.Context, userID uuid.UUID) ([
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.KeepLast,
 			&i.FeedName,
 			&i.UserName,
 		); err != nil {
@@ -124,6 +129,52 @@ func (q *Queries) GetFeedFollowForUser(ctx context.Context, userID uuid.UUID) ([
 	return items, nil
 }
 
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, keep_last)
+SELECT gen_random_uuid(), created_at, $1::timestamptz, user_id, $2::uuid, keep_last
+FROM feed_follows
+WHERE feed_id = $3
+ON CONFLICT (user_id, feed_id) DO NOTHING
+`
+
+type MoveFeedFollowsParams struct {
+	UpdatedAt  time.Time
+	ToFeedID   uuid.UUID
+	FromFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.UpdatedAt, arg.ToFeedID, arg.FromFeedID)
+	return err
+}
+
+const setFollowKeepLast = `-- name: SetFollowKeepLast :execrows
+UPDATE feed_follows
+SET keep_last = $1,
+    updated_at = $2
+WHERE user_id = $3 AND feed_id = $4
+`
+
+type SetFollowKeepLastParams struct {
+	KeepLast  sql.NullInt32
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+}
+
+func (q *Queries) SetFollowKeepLast(ctx context.Context, arg SetFollowKeepLastParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFollowKeepLast,
+		arg.KeepLast,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const unfollow = `-- name: Unfollow :exec
 DELETE FROM feed_follows
 WHERE user_id = $1 AND feed_id = $2
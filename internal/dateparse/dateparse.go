@@ -0,0 +1,205 @@
+// Package dateparse parses the many date formats found in feeds: RFC 822
+// and its variations, ISO 8601 with or without seconds or zone, time zone
+// abbreviations, "GMT+2" style offsets and month names in several
+// languages.
+package dateparse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	// comments drops parenthesized remarks such as "(PST)" or "(Central
+	// European Summer Time)".
+	comments = regexp.MustCompile(`\([^)]*\)`)
+	// offsetToken matches a numeric offset, optionally after GMT or UTC,
+	// written apart from the time: "+0200", "-05:00", "GMT+2", "UTC+5:30".
+	offsetToken = regexp.MustCompile(`(?i)^(?:gmt|utc|ut)?([+-])(\d{1,2})(?::?(\d{2}))?$`)
+	// ordinalDay matches days such as "2nd", "1er" or "2." (German).
+	ordinalDay   = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th|er|e|\.)$`)
+	clockToken   = regexp.MustCompile(`^(\d{1,2}):(\d{2})(?::(\d{2}))?(?:[.,]\d+)?$`)
+	isoDateToken = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})`)
+)
+
+// Parse parses a date as found in a feed. Values without a zone are taken
+// to be in UTC. When no layout matches, the year, month, day, time and
+// zone are picked out of the words of the value.
+func Parse(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+	// Well-formed dates need no normalization. RFC 1123 with a zone
+	// abbreviation is left to the layouts, as time.Parse gives unknown
+	// abbreviations a zero offset.
+	for _, layout := range []string{time.RFC3339, time.RFC1123Z} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	tokens := normalize(value)
+	normalized := strings.Join(tokens, " ")
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t, nil
+		}
+	}
+	if t, ok := fuzzy(tokens); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", value)
+}
+
+// normalize splits value into words and rewrites them into the forms used
+// by layouts: English month abbreviations, numeric "+hhmm" zones and bare
+// day numbers. Weekdays, commas, remarks and filler words are dropped.
+func normalize(value string) []string {
+	value = comments.ReplaceAllString(value, " ")
+	value = strings.ReplaceAll(value, ",", " ")
+	var tokens []string
+	var monthIndexes []int
+	for i, token := range strings.Fields(value) {
+		lower := strings.ToLower(token)
+		if fillers[lower] {
+			continue
+		}
+		if month, ok := lookupMonth(lower); ok {
+			monthIndexes = append(monthIndexes, len(tokens))
+			tokens = append(tokens, month.String()[:3])
+			continue
+		}
+		switch lower {
+		case "am", "a.m.":
+			tokens = append(tokens, "AM")
+			continue
+		case "pm", "p.m.":
+			tokens = append(tokens, "PM")
+			continue
+		}
+		if offset, ok := zones[lower]; ok {
+			tokens = append(tokens, formatOffset(offset))
+			continue
+		}
+		if m := offsetToken.FindStringSubmatch(token); m != nil && (i > 0 || len(token) > 3) {
+			hours, _ := strconv.Atoi(m[2])
+			minutes, _ := strconv.Atoi(m[3])
+			offset := hours*60 + minutes
+			if m[1] == "-" {
+				offset = -offset
+			}
+			tokens = append(tokens, formatOffset(offset))
+			continue
+		}
+		if m := ordinalDay.FindStringSubmatch(lower); m != nil {
+			tokens = append(tokens, m[1])
+			continue
+		}
+		// Weekdays, in whatever language, are the only words left before
+		// the date
+		if len(tokens) == 0 && isWord(token) {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	// A leading weekday that reads as a month, like the Spanish "mar."
+	// (martes), comes before the real month
+	if len(monthIndexes) > 1 && monthIndexes[0] == 0 {
+		tokens = tokens[1:]
+	}
+	return tokens
+}
+
+func lookupMonth(word string) (time.Month, bool) {
+	month, ok := months[strings.TrimSuffix(word, ".")]
+	return month, ok
+}
+
+func isWord(token string) bool {
+	for _, r := range token {
+		if !unicode.IsLetter(r) && r != '-' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+func formatOffset(minutes int) string {
+	sign := '+'
+	if minutes < 0 {
+		sign = '-'
+		minutes = -minutes
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, minutes/60, minutes%60)
+}
+
+// fuzzy builds a date from normalized words that match no layout, such as
+// "Posted on Jan 2 2006 around 15:04". It needs at least a year, a month
+// name and a day.
+func fuzzy(tokens []string) (time.Time, bool) {
+	year, day, hour, minute, second := 0, 0, 0, 0, 0
+	var month time.Month
+	loc := time.UTC
+	pm, am := false, false
+	for _, token := range tokens {
+		if m := isoDateToken.FindStringSubmatch(token); m != nil && year == 0 {
+			year, _ = strconv.Atoi(m[1])
+			n, _ := strconv.Atoi(m[2])
+			month = time.Month(n)
+			day, _ = strconv.Atoi(m[3])
+			continue
+		}
+		if m := clockToken.FindStringSubmatch(token); m != nil {
+			hour, _ = strconv.Atoi(m[1])
+			minute, _ = strconv.Atoi(m[2])
+			second, _ = strconv.Atoi(m[3])
+			continue
+		}
+		if m, ok := lookupMonth(strings.ToLower(token)); ok && month == 0 {
+			month = m
+			continue
+		}
+		switch token {
+		case "AM":
+			am = true
+			continue
+		case "PM":
+			pm = true
+			continue
+		}
+		if len(token) == 5 && (token[0] == '+' || token[0] == '-') {
+			if t, err := time.Parse("-0700", token); err == nil {
+				loc = t.Location()
+			}
+			continue
+		}
+		n, err := strconv.Atoi(token)
+		if err != nil {
+			continue
+		}
+		switch {
+		case len(token) == 4 && year == 0:
+			year = n
+		case len(token) <= 2 && n >= 1 && n <= 31 && day == 0:
+			day = n
+		}
+	}
+	if year == 0 || month < time.January || month > time.December || day == 0 || hour > 23 || minute > 59 || second > 60 {
+		return time.Time{}, false
+	}
+	if pm && hour < 12 {
+		hour += 12
+	} else if am && hour == 12 {
+		hour = 0
+	}
+	t := time.Date(year, month, day, hour, minute, second, 0, loc)
+	if t.Day() != day {
+		// The day does not exist in that month
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,80 @@
+package dateparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"RFC 1123 with offset", "Mon, 02 Jan 2006 15:04:05 -0700", "2006-01-02T22:04:05Z"},
+		{"RFC 1123 with GMT", "Mon, 02 Jan 2006 15:04:05 GMT", "2006-01-02T15:04:05Z"},
+		{"US zone abbreviation", "Mon, 02 Jan 2006 15:04:05 EST", "2006-01-02T20:04:05Z"},
+		{"European zone abbreviation", "Mon, 02 Jan 2006 15:04:05 CEST", "2006-01-02T13:04:05Z"},
+		{"India zone abbreviation", "Mon, 02 Jan 2006 15:04:05 IST", "2006-01-02T09:34:05Z"},
+		{"two-digit year and dashes", "02-Jan-06 15:04:05 PST", "2006-01-02T23:04:05Z"},
+		{"single-digit day", "Mon, 2 Jan 2006 15:04:05 +0000", "2006-01-02T15:04:05Z"},
+		{"missing seconds", "Mon, 02 Jan 2006 15:04 +0100", "2006-01-02T14:04:00Z"},
+		{"GMT offset in hours", "Mon, 02 Jan 2006 15:04:05 GMT+2", "2006-01-02T13:04:05Z"},
+		{"UTC offset with minutes", "2006-01-02 15:04:05 UTC+05:30", "2006-01-02T09:34:05Z"},
+		{"parenthesized zone name", "Mon, 02 Jan 2006 15:04:05 -0800 (PST)", "2006-01-02T23:04:05Z"},
+		{"RFC 3339", "2006-01-02T15:04:05+02:00", "2006-01-02T13:04:05Z"},
+		{"RFC 3339 with fraction", "2006-01-02T15:04:05.123Z", "2006-01-02T15:04:05.123Z"},
+		{"ISO 8601 without seconds", "2006-01-02T15:04Z", "2006-01-02T15:04:00Z"},
+		{"ISO 8601 hours-only offset", "2006-01-02T15:04:05-07", "2006-01-02T22:04:05Z"},
+		{"ISO 8601 basic format", "20060102T150405Z", "2006-01-02T15:04:05Z"},
+		{"ISO 8601 without zone", "2006-01-02 15:04:05", "2006-01-02T15:04:05Z"},
+		{"W3C-DTF date only", "2006-01-02", "2006-01-02T00:00:00Z"},
+		{"written date with ordinal", "January 2nd, 2006 at 3:04 pm", "2006-01-02T15:04:00Z"},
+		{"JavaScript Date string", "Mon Jan 02 2006 15:04:05 GMT+0100 (Central European Standard Time)", "2006-01-02T14:04:05Z"},
+		{"ctime", "Mon Jan  2 15:04:05 2006", "2006-01-02T15:04:05Z"},
+		{"French", "lun., 02 janv. 2006 15:04:05 +0100", "2006-01-02T14:04:05Z"},
+		{"French written date", "2 février 2006", "2006-02-02T00:00:00Z"},
+		{"German with zone abbreviation", "Di, 03 Mär 2006 10:00:00 MEZ", "2006-03-03T09:00:00Z"},
+		{"German numeric date", "02.01.2006 15:04", "2006-01-02T15:04:00Z"},
+		{"Spanish weekday that reads as a month", "mar., 03 ene. 2006 10:00:00 +0000", "2006-01-03T10:00:00Z"},
+		{"Spanish written date", "2 de enero de 2006", "2006-01-02T00:00:00Z"},
+		{"date within a sentence", "Posted on Jan 2 2006 around 15:04", "2006-01-02T15:04:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := time.Parse(time.RFC3339Nano, tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.value, err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.value, got.UTC(), want)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"empty", ""},
+		{"blank", "   "},
+		{"not a date", "garbage"},
+		{"day past the end of the month", "31 Feb 2006"},
+		{"day past the end of the month with time", "Fri, 31 Feb 2006 15:04:05 GMT"},
+		{"month without year", "January 2"},
+		{"hour out of range", "Jan 2 2006 25:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Parse(tt.value); err == nil {
+				t.Errorf("Parse(%q) = %v, want an error", tt.value, got)
+			}
+		})
+	}
+}
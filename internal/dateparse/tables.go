@@ -0,0 +1,172 @@
+package dateparse
+
+import (
+	"strings"
+	"time"
+)
+
+// layouts are tried in order on the normalized value, so the layouts with
+// a time and zone come before the shorter ones they extend.
+var layouts = buildLayouts()
+
+func buildLayouts() []string {
+	list := []string{
+		// Offsets with hours only and the basic ISO 8601 format
+		"2006-01-02T15:04:05-07",
+		"20060102T150405Z0700",
+		// ctime, Unix date(1) and JavaScript Date.toString()
+		"Jan 2 15:04:05 -0700 2006",
+		"Jan 2 15:04:05 2006",
+	}
+	dates := []string{
+		"2 Jan 2006",
+		"2 Jan 06",
+		"Jan 2 2006",
+		"Jan 2 06",
+		"2006 Jan 2",
+		"2-Jan-2006",
+		"2-Jan-06",
+		"2006-01-02",
+		"2006/01/02",
+		"2006.01.02",
+		"2.1.2006",
+	}
+	// ISO 8601 and W3C-DTF (dc:date) may omit the seconds or the time
+	// entirely. Fractional seconds are accepted by time.Parse without a
+	// layout of their own.
+	times := []string{
+		"T15:04:05",
+		"T15:04",
+		" 15:04:05",
+		" 15:04",
+		" 3:04:05 PM",
+		" 3:04 PM",
+	}
+	zones := []string{" -0700", "Z07:00", "-0700", ""}
+	for _, date := range dates {
+		for _, clock := range times {
+			if clock[0] == 'T' && !strings.HasPrefix(date, "2006-") {
+				continue
+			}
+			for _, zone := range zones {
+				// A zone glued to the time only follows ISO-style dates
+				if zone != "" && zone[0] != ' ' && !strings.HasPrefix(date, "2006") {
+					continue
+				}
+				list = append(list, date+clock+zone)
+			}
+		}
+		list = append(list, date)
+	}
+	return list
+}
+
+// months maps English and localized month names and abbreviations, in
+// lower case and without a trailing period, to their month.
+var months = map[string]time.Month{
+	// English
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+	// French
+	"janvier": time.January, "janv": time.January,
+	"février": time.February, "fevrier": time.February, "févr": time.February, "fevr": time.February, "fév": time.February,
+	"mars":  time.March,
+	"avril": time.April, "avr": time.April,
+	"mai":     time.May,
+	"juin":    time.June,
+	"juillet": time.July, "juil": time.July,
+	"août": time.August, "aout": time.August,
+	"septembre": time.September,
+	"octobre":   time.October,
+	"novembre":  time.November,
+	"décembre":  time.December, "decembre": time.December, "déc": time.December,
+	// German
+	"januar": time.January, "jänner": time.January, "jän": time.January,
+	"februar": time.February,
+	"märz":    time.March, "maerz": time.March, "mär": time.March, "mrz": time.March,
+	"juni":    time.June,
+	"juli":    time.July,
+	"oktober": time.October, "okt": time.October,
+	"dezember": time.December, "dez": time.December,
+	// Spanish
+	"enero": time.January, "ene": time.January,
+	"febrero": time.February,
+	"marzo":   time.March,
+	"abril":   time.April, "abr": time.April,
+	"mayo":   time.May,
+	"junio":  time.June,
+	"julio":  time.July,
+	"agosto": time.August, "ago": time.August,
+	"septiembre": time.September, "setiembre": time.September,
+	"octubre":   time.October,
+	"noviembre": time.November,
+	"diciembre": time.December, "dic": time.December,
+	// Italian
+	"gennaio": time.January, "gen": time.January,
+	"febbraio": time.February,
+	"aprile":   time.April,
+	"maggio":   time.May, "mag": time.May,
+	"giugno": time.June, "giu": time.June,
+	"luglio": time.July, "lug": time.July,
+	"settembre": time.September, "set": time.September,
+	"ottobre": time.October, "ott": time.October,
+	"dicembre": time.December,
+	// Portuguese
+	"janeiro":   time.January,
+	"fevereiro": time.February, "fev": time.February,
+	"março": time.March, "marco": time.March,
+	"maio":     time.May,
+	"junho":    time.June,
+	"julho":    time.July,
+	"setembro": time.September,
+	"outubro":  time.October, "out": time.October,
+	"novembro": time.November,
+	"dezembro": time.December,
+	// Dutch
+	"januari":  time.January,
+	"februari": time.February,
+	"maart":    time.March, "mrt": time.March,
+	"mei":      time.May,
+	"augustus": time.August,
+}
+
+// zones maps time zone abbreviations to their offset from UTC in minutes.
+// Abbreviations shared by several zones map to the one most often seen in
+// feeds: CST is US Central, IST is India and BST is British Summer Time.
+var zones = map[string]int{
+	"z": 0, "ut": 0, "utc": 0, "gmt": 0, "wet": 0,
+	"west": 60, "bst": 60, "cet": 60, "met": 60, "mez": 60, "wat": 60,
+	"cest": 120, "mest": 120, "mesz": 120, "eet": 120, "cat": 120, "sast": 120,
+	"eest": 180, "msk": 180, "eat": 180, "ast": -240, "adt": -180,
+	"est": -300, "edt": -240,
+	"cst": -360, "cdt": -300,
+	"mst": -420, "mdt": -360,
+	"pst": -480, "pdt": -420,
+	"akst": -540, "akdt": -480,
+	"hst": -600,
+	"nst": -210, "ndt": -150,
+	"brt": -180, "art": -180,
+	"pkt": 300, "ist": 330, "npt": 345,
+	"ict": 420, "wib": 420,
+	"hkt": 480, "sgt": 480, "pht": 480, "awst": 480,
+	"jst": 540, "kst": 540,
+	"acst": 570, "acdt": 630,
+	"aest": 600, "aedt": 660,
+	"nzst": 720, "nzdt": 780,
+}
+
+// fillers are words that join the parts of a written date, as in
+// "2 de enero de 2006" or "January 2, 2006 at 3:04 PM".
+var fillers = map[string]bool{
+	"de": true, "del": true, "at": true, "of": true, "um": true, "à": true, "om": true, "alle": true, "às": true,
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// JSONFeed is a JSON Feed 1.0/1.1 document (https://jsonfeed.org/version/1.1).
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            json.RawMessage      `json:"id"`
+	URL           string               `json:"url"`
+	ExternalURL   string               `json:"external_url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	ContentText   string               `json:"content_text"`
+	Summary       string               `json:"summary"`
+	DatePublished string               `json:"date_published"`
+	DateModified  string               `json:"date_modified"`
+	Image         string               `json:"image"`
+	Tags          []string             `json:"tags"`
+	Authors       []JSONFeedAuthor     `json:"authors"`
+	Author        *JSONFeedAuthor      `json:"author"`
+	Attachments   []JSONFeedAttachment `json:"attachments"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type JSONFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	Title             string  `json:"title"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+// jsonFeedID returns an item id as a string. The spec requires a string,
+// but some publishers emit numbers.
+func jsonFeedID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+func (f *JSONFeed) normalize() *ParsedFeed {
+	feed := &ParsedFeed{
+		Title:       f.Title,
+		Link:        f.HomePageURL,
+		Description: f.Description,
+	}
+	for _, item := range f.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+		description := item.Summary
+		if description == "" {
+			description = item.ContentHTML
+		}
+		if description == "" {
+			description = item.ContentText
+		}
+		content := item.ContentHTML
+		if content == "" {
+			content = item.ContentText
+		}
+		pubDate := item.DatePublished
+		if pubDate == "" {
+			pubDate = item.DateModified
+		}
+		authors := item.Authors
+		if len(authors) == 0 && item.Author != nil {
+			// JSON Feed 1.0 used a single author object.
+			authors = []JSONFeedAuthor{*item.Author}
+		}
+		var names []string
+		for _, author := range authors {
+			if author.Name != "" {
+				names = append(names, author.Name)
+			}
+		}
+		var enclosures []FeedEnclosure
+		for _, attachment := range item.Attachments {
+			enclosures = append(enclosures, FeedEnclosure{
+				URL:      attachment.URL,
+				MimeType: attachment.MimeType,
+				Length:   attachment.SizeInBytes,
+				Duration: int(attachment.DurationInSeconds),
+			})
+		}
+		enclosures = withImage(mergeEnclosures(enclosures), item.Image)
+		feed.Items = append(feed.Items, FeedItem{
+			GUID:        jsonFeedID(item.ID),
+			Title:       item.Title,
+			Link:        link,
+			Description: description,
+			Content:     content,
+			PubDate:     pubDate,
+			Author:      strings.Join(names, ", "),
+			Categories:  item.Tags,
+			Enclosures:  enclosures,
+		})
+	}
+	return feed
+}
@@ -22,9 +22,28 @@ func main() {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	client, err := newHTTPClient(cfg.Http)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	limiter, err := newHostLimiters(cfg.Http)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	location, err := cfg.DisplayLocation()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	st := state{
-		db:  database.New(db),
-		cfg: &cfg,
+		db:       database.New(db),
+		conn:     db,
+		cfg:      &cfg,
+		client:   client,
+		limiter:  limiter,
+		location: location,
 	}
 	cmds := commands{
 		cmds: make(map[string]func(*state, command) error),
@@ -37,10 +56,21 @@ func main() {
 	cmds.register("agg", handlerAgg)
 	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
 	cmds.register("feeds", handlerFeeds)
+	cmds.register("feedstatus", handlerFeedStatus)
+	cmds.register("enablefeed", handlerEnableFeed)
+	cmds.register("setauth", middlewareLoggedIn(handlerSetAuth))
 	cmds.register("follow", middlewareLoggedIn(handlerFollow))
 	cmds.register("following", middlewareLoggedIn(handlerFollowing))
 	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
 	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
+	cmds.register("read", middlewareLoggedIn(handlerRead))
+	cmds.register("markread", middlewareLoggedIn(handlerMarkRead))
+	cmds.register("star", middlewareLoggedIn(handlerStar))
+	cmds.register("unstar", middlewareLoggedIn(handlerUnstar))
+	cmds.register("starred", middlewareLoggedIn(handlerStarred))
+	cmds.register("search", middlewareLoggedIn(handlerSearch))
+	cmds.register("download", middlewareLoggedIn(handlerDownload))
+	cmds.register("keeplast", middlewareLoggedIn(handlerKeepLast))
 	args := os.Args
 	if len(args) < 2 {
 		fmt.Println("enter command")
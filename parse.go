@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"strings"
+)
+
+// ParsedFeed is the format-independent result of parsing a feed document.
+type ParsedFeed struct {
+	Title       string
+	Link        string
+	Description string
+	Items       []FeedItem
+	Refresh     refreshHints
+	// Warning describes problems the lenient parser recovered from.
+	Warning string
+}
+
+type FeedItem struct {
+	GUID        string
+	Title       string
+	Link        string
+	Description string
+	// Content is the full HTML of the item when the feed has more than
+	// the description.
+	Content     string
+	PubDate     string
+	Author      string
+	Categories  []string
+	CommentsURL string
+	Enclosures  []FeedEnclosure
+}
+
+type FeedEnclosure struct {
+	URL      string
+	MimeType string
+	// Length is in bytes and Duration in seconds; 0 when unknown.
+	Length   int64
+	Duration int
+	Image    string
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// isJSONFeed reports whether the response looks like a JSON Feed, either
+// from its Content-Type or, for servers that send text/plain or nothing,
+// from the first byte of the body.
+func isJSONFeed(contentType string, dat []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/feed+json", "application/json":
+		return true
+	}
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(dat, utf8BOM))
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// parseFeed parses a feed document of any supported format, keeping at most
+// maxItems items.
+func parseFeed(dat []byte, contentType string, maxItems int) (*ParsedFeed, error) {
+	dat, err := toUTF8(dat, contentType)
+	if err != nil {
+		return nil, err
+	}
+	if isJSONFeed(contentType, dat) {
+		var jsonFeed JSONFeed
+		if err := json.Unmarshal(dat, &jsonFeed); err != nil {
+			return nil, fmt.Errorf("failed to decode JSON feed: %w", err)
+		}
+		if !strings.HasPrefix(jsonFeed.Version, "https://jsonfeed.org/version/") {
+			return nil, fmt.Errorf("unsupported JSON feed version: %q", jsonFeed.Version)
+		}
+		feed := jsonFeed.normalize()
+		if len(feed.Items) > maxItems {
+			feed.Items = feed.Items[:maxItems]
+		}
+		return unescapeFeed(feed), nil
+	}
+
+	feed, err := decodeXMLFeed(dat, true, maxItems)
+	if err == nil {
+		return unescapeFeed(feed), nil
+	}
+	// Retry leniently, keeping whatever can be decoded before the first
+	// unrecoverable error.
+	strictErr := err
+	feed, err = decodeXMLFeed(sanitizeXML(dat), false, maxItems)
+	if feed == nil || (err != nil && feed.Title == "" && len(feed.Items) == 0) {
+		return nil, strictErr
+	}
+	feed.Warning = fmt.Sprintf("malformed feed: %v", strictErr)
+	if err != nil {
+		feed.Warning += fmt.Sprintf("; kept %d items decoded before: %v", len(feed.Items), err)
+	}
+	return unescapeFeed(feed), nil
+}
+
+// errItemLimit stops decoding once the maximum number of items is read.
+var errItemLimit = errors.New("item limit reached")
+
+type itemLimit struct {
+	remaining int
+}
+
+func (l *itemLimit) take() error {
+	if l.remaining <= 0 {
+		return errItemLimit
+	}
+	l.remaining--
+	return nil
+}
+
+// decodeXMLFeed decodes an RSS, RSS 1.0 or Atom document token by token,
+// decoding one item at a time and stopping after maxItems items. If
+// decoding fails part way, the items decoded so far are returned along
+// with the error.
+func decodeXMLFeed(dat []byte, strict bool, maxItems int) (*ParsedFeed, error) {
+	decoder := newXMLDecoder(dat, strict)
+	var root xml.StartElement
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read feed document: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			root = start
+			break
+		}
+	}
+
+	limit := &itemLimit{remaining: maxItems}
+	var (
+		feed *ParsedFeed
+		err  error
+	)
+	switch root.Name.Local {
+	case "rss":
+		feed, err = decodeRSS(decoder, limit)
+	case "RDF":
+		feed, err = decodeRDF(decoder, limit)
+	case "feed":
+		feed, err = decodeAtom(decoder, limit)
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root.Name.Local)
+	}
+	if errors.Is(err, errItemLimit) {
+		err = nil
+	}
+	return feed, err
+}
+
+// decodeChildren calls fn for each child element of the element whose
+// start tag was just read, up to its end tag. fn must consume the child,
+// e.g. with DecodeElement or Skip.
+func decodeChildren(decoder *xml.Decoder, fn func(start xml.StartElement) error) error {
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if err := fn(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// decodeItem decodes a single item or entry element, counting it against
+// limit.
+func decodeItem[T any](decoder *xml.Decoder, start xml.StartElement, limit *itemLimit) (T, error) {
+	var item T
+	if err := limit.take(); err != nil {
+		return item, err
+	}
+	err := decoder.DecodeElement(&item, &start)
+	return item, err
+}
+
+func unescapeFeed(feed *ParsedFeed) *ParsedFeed {
+	feed.Title = html.UnescapeString(feed.Title)
+	feed.Description = html.UnescapeString(feed.Description)
+	for i, item := range feed.Items {
+		feed.Items[i].Title = html.UnescapeString(item.Title)
+		feed.Items[i].Description = html.UnescapeString(item.Description)
+	}
+	return feed
+}
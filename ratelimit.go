@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Corogura/gator/internal/config"
+	"golang.org/x/time/rate"
+)
+
+// throttledError is returned by fetchFeed when a host asked us to slow
+// down with 429 or 503 and a Retry-After header, or while an earlier
+// Retry-After for the host has not expired. The feed is retried at Until
+// without counting a failure.
+type throttledError struct {
+	Host   string
+	Status string
+	Until  time.Time
+	// Location is the display time zone Until is printed in.
+	Location *time.Location
+}
+
+func (e *throttledError) Error() string {
+	return fmt.Sprintf("%s throttled by %s until %s", e.Host, e.Status, e.Until.In(e.Location).Format(displayTimeLayout))
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP
+// date. The delay is capped like the other scheduling hints.
+func retryAfter(header http.Header, now time.Time) (time.Time, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return time.Time{}, false
+	}
+	var until time.Time
+	if seconds, err := strconv.Atoi(value); err == nil {
+		until = now.Add(time.Duration(seconds) * time.Second)
+	} else if date, err := http.ParseTime(value); err == nil {
+		until = date
+	} else {
+		return time.Time{}, false
+	}
+	if until.Before(now) {
+		return now, true
+	}
+	if limit := now.Add(maxHintedInterval); until.After(limit) {
+		return limit, true
+	}
+	return until, true
+}
+
+// hostLimiters keeps a token bucket per host so that feeds sharing a host
+// are fetched politely however many run in parallel.
+type hostLimiters struct {
+	cfg config.HTTPConfig
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	// blocked holds the Retry-After time of hosts that throttled us.
+	blocked map[string]*throttledError
+}
+
+func newHostLimiters(cfg config.HTTPConfig) (*hostLimiters, error) {
+	// Check every configured limit up front rather than on first use
+	if _, _, err := cfg.HostRateLimit(""); err != nil {
+		return nil, err
+	}
+	for host := range cfg.Host_rate_limits {
+		if _, _, err := cfg.HostRateLimit(host); err != nil {
+			return nil, err
+		}
+	}
+	return &hostLimiters{
+		cfg:      cfg,
+		limiters: make(map[string]*rate.Limiter),
+		blocked:  make(map[string]*throttledError),
+	}, nil
+}
+
+// wait blocks until a request to host is allowed. It fails at once with a
+// *throttledError if the host asked us to wait longer.
+func (h *hostLimiters) wait(ctx context.Context, host string) error {
+	host = strings.ToLower(host)
+	h.mu.Lock()
+	if blocked, ok := h.blocked[host]; ok {
+		if time.Now().Before(blocked.Until) {
+			h.mu.Unlock()
+			return blocked
+		}
+		delete(h.blocked, host)
+	}
+	limiter, ok := h.limiters[host]
+	if !ok {
+		interval, burst, err := h.cfg.HostRateLimit(host)
+		if err != nil {
+			h.mu.Unlock()
+			return err
+		}
+		every := rate.Inf
+		if interval > 0 {
+			every = rate.Every(interval)
+		}
+		limiter = rate.NewLimiter(every, burst)
+		h.limiters[host] = limiter
+	}
+	h.mu.Unlock()
+	return limiter.Wait(ctx)
+}
+
+// block holds back further requests to the host until e.Until.
+func (h *hostLimiters) block(e *throttledError) {
+	host := strings.ToLower(e.Host)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if current, ok := h.blocked[host]; !ok || current.Until.Before(e.Until) {
+		h.blocked[host] = e
+	}
+}
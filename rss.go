@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	atomNS    = "http://www.w3.org/2005/Atom"
+	dcNS      = "http://purl.org/dc/elements/1.1/"
+	syNS      = "http://purl.org/rss/1.0/modules/syndication/"
+	itunesNS  = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	mediaNS   = "http://search.yahoo.com/mrss/"
+	contentNS = "http://purl.org/rss/1.0/modules/content/"
+	rdfNS     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	// RSS 0.90 and 1.0 put their elements in these namespaces, RSS 0.9x
+	// and 2.0 in none.
+	rss090NS = "http://my.netscape.com/rdf/simple/0.9/"
+	rss10NS  = "http://purl.org/rss/1.0/"
+)
+
+type RSSFeed struct {
+	Channel RSSChannel `xml:"channel"`
+}
+
+// RDFFeed is an RSS 1.0 document, where items are siblings of the channel
+// under the rdf:RDF root rather than children of it.
+type RDFFeed struct {
+	Channel RSSChannel `xml:"channel"`
+	Item    []RSSItem  `xml:"item"`
+}
+
+type RSSChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Item        []RSSItem `xml:"item"`
+	// ItunesImage is the podcast's cover art.
+	ItunesImage ItunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	rssChannelHints
+}
+
+type RSSItem struct {
+	About       string         `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	GUID        string         `xml:"guid"`
+	Title       string         `xml:"title"`
+	Link        string         `xml:"link"`
+	Description string         `xml:"description"`
+	Content     string         `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Comments    []string       `xml:"comments"`
+	PubDate     string         `xml:"pubDate"`
+	Author      string         `xml:"author"`
+	Category    []string       `xml:"category"`
+	DCDate      string         `xml:"http://purl.org/dc/elements/1.1/ date"`
+	DCCreator   []string       `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	DCSubject   []string       `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Enclosure   []RSSEnclosure `xml:"enclosure"`
+	ItemMedia
+}
+
+// UnmarshalXML decodes the RSS elements of an item only from the RSS
+// namespaces, so that extensions with the same local names, such as
+// itunes:title, media:description or atom:link, do not overwrite them.
+func (item *RSSItem) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == rdfNS && attr.Name.Local == "about" {
+			item.About = attr.Value
+		}
+	}
+	return decodeChildren(decoder, func(start xml.StartElement) error {
+		switch start.Name.Space {
+		case "", rss090NS, rss10NS:
+			return item.decodeRSS(decoder, start)
+		case contentNS:
+			if start.Name.Local == "encoded" {
+				return decoder.DecodeElement(&item.Content, &start)
+			}
+		case dcNS:
+			switch start.Name.Local {
+			case "date":
+				return decoder.DecodeElement(&item.DCDate, &start)
+			case "creator":
+				return decodeAppend(decoder, start, &item.DCCreator)
+			case "subject":
+				return decodeAppend(decoder, start, &item.DCSubject)
+			}
+		case itunesNS, mediaNS:
+			return item.ItemMedia.decode(decoder, start)
+		}
+		return decoder.Skip()
+	})
+}
+
+func (item *RSSItem) decodeRSS(decoder *xml.Decoder, start xml.StartElement) error {
+	switch start.Name.Local {
+	case "guid":
+		return decoder.DecodeElement(&item.GUID, &start)
+	case "title":
+		return decoder.DecodeElement(&item.Title, &start)
+	case "link":
+		return decoder.DecodeElement(&item.Link, &start)
+	case "description":
+		return decoder.DecodeElement(&item.Description, &start)
+	case "comments":
+		return decodeAppend(decoder, start, &item.Comments)
+	case "pubDate":
+		return decoder.DecodeElement(&item.PubDate, &start)
+	case "author":
+		return decoder.DecodeElement(&item.Author, &start)
+	case "category":
+		return decodeAppend(decoder, start, &item.Category)
+	case "enclosure":
+		var enclosure RSSEnclosure
+		err := decoder.DecodeElement(&enclosure, &start)
+		item.Enclosure = append(item.Enclosure, enclosure)
+		return err
+	}
+	return decoder.Skip()
+}
+
+// decodeAppend decodes the text of an element that may repeat.
+func decodeAppend(decoder *xml.Decoder, start xml.StartElement, values *[]string) error {
+	var value string
+	err := decoder.DecodeElement(&value, &start)
+	*values = append(*values, value)
+	return err
+}
+
+func (item RSSItem) normalize() FeedItem {
+	guid := strings.TrimSpace(item.GUID)
+	if guid == "" {
+		guid = strings.TrimSpace(item.About)
+	}
+	pubDate := strings.TrimSpace(item.PubDate)
+	if pubDate == "" {
+		pubDate = strings.TrimSpace(item.DCDate)
+	}
+	author := strings.TrimSpace(item.Author)
+	if author == "" {
+		author = joinNonEmpty(item.DCCreator, ", ")
+	}
+	var categories []string
+	for _, category := range append(item.Category, item.DCSubject...) {
+		if category = strings.TrimSpace(category); category != "" {
+			categories = append(categories, category)
+		}
+	}
+	commentsURL := ""
+	for _, comments := range item.Comments {
+		if comments = strings.TrimSpace(comments); strings.Contains(comments, "://") {
+			commentsURL = comments
+			break
+		}
+	}
+	var enclosures []FeedEnclosure
+	for _, enclosure := range item.Enclosure {
+		enclosures = append(enclosures, FeedEnclosure{
+			URL:      strings.TrimSpace(enclosure.URL),
+			MimeType: strings.TrimSpace(enclosure.Type),
+			Length:   parseLength(enclosure.Length),
+		})
+	}
+	return FeedItem{
+		GUID:        guid,
+		Title:       item.Title,
+		Link:        strings.TrimSpace(item.Link),
+		Description: item.Description,
+		Content:     item.Content,
+		PubDate:     pubDate,
+		Author:      author,
+		Categories:  categories,
+		CommentsURL: commentsURL,
+		Enclosures:  item.ItemMedia.enclosures(enclosures),
+	}
+}
+
+func (f *RSSFeed) normalize() *ParsedFeed {
+	feed := &ParsedFeed{
+		Title:       f.Channel.Title,
+		Link:        f.Channel.Link,
+		Description: f.Channel.Description,
+		Refresh:     f.Channel.rssChannelHints.normalize(),
+	}
+	for _, item := range f.Channel.Item {
+		normalized := item.normalize()
+		normalized.Enclosures = withImage(normalized.Enclosures, strings.TrimSpace(f.Channel.ItunesImage.Href))
+		feed.Items = append(feed.Items, normalized)
+	}
+	return feed
+}
+
+func (f *RDFFeed) normalize() *ParsedFeed {
+	feed := &ParsedFeed{
+		Title:       f.Channel.Title,
+		Link:        f.Channel.Link,
+		Description: f.Channel.Description,
+		Refresh:     f.Channel.rssChannelHints.normalize(),
+	}
+	for _, item := range append(f.Channel.Item, f.Item...) {
+		normalized := item.normalize()
+		normalized.Enclosures = withImage(normalized.Enclosures, strings.TrimSpace(f.Channel.ItunesImage.Href))
+		feed.Items = append(feed.Items, normalized)
+	}
+	return feed
+}
+
+// isRSSNamespace reports whether space is that of RSS elements rather than
+// of an extension.
+func isRSSNamespace(space string) bool {
+	switch space {
+	case "", rss090NS, rss10NS:
+		return true
+	}
+	return false
+}
+
+func decodeRSS(decoder *xml.Decoder, limit *itemLimit) (*ParsedFeed, error) {
+	var rss RSSFeed
+	err := decodeChildren(decoder, func(start xml.StartElement) error {
+		if start.Name.Local == "channel" && isRSSNamespace(start.Name.Space) {
+			return rss.Channel.decode(decoder, limit)
+		}
+		return decoder.Skip()
+	})
+	return rss.normalize(), err
+}
+
+func decodeRDF(decoder *xml.Decoder, limit *itemLimit) (*ParsedFeed, error) {
+	var rdf RDFFeed
+	err := decodeChildren(decoder, func(start xml.StartElement) error {
+		if !isRSSNamespace(start.Name.Space) {
+			return decoder.Skip()
+		}
+		switch start.Name.Local {
+		case "channel":
+			return rdf.Channel.decode(decoder, limit)
+		case "item":
+			item, err := decodeItem[RSSItem](decoder, start, limit)
+			if err != nil {
+				return err
+			}
+			rdf.Item = append(rdf.Item, item)
+			return nil
+		}
+		return decoder.Skip()
+	})
+	return rdf.normalize(), err
+}
+
+// decode reads the children of a <channel> element. Only RSS 0.9x/2.0
+// channels contain items; RSS 1.0 puts them next to the channel.
+func (ch *RSSChannel) decode(decoder *xml.Decoder, limit *itemLimit) error {
+	return decodeChildren(decoder, func(start xml.StartElement) error {
+		switch start.Name.Space {
+		case syNS:
+			switch start.Name.Local {
+			case "updatePeriod":
+				return decoder.DecodeElement(&ch.UpdatePeriod, &start)
+			case "updateFrequency":
+				return decoder.DecodeElement(&ch.UpdateFrequency, &start)
+			}
+			return decoder.Skip()
+		case itunesNS:
+			if start.Name.Local == "image" {
+				return decoder.DecodeElement(&ch.ItunesImage, &start)
+			}
+			return decoder.Skip()
+		case "", rss090NS, rss10NS:
+			return ch.decodeRSS(decoder, start, limit)
+		}
+		return decoder.Skip()
+	})
+}
+
+// decodeRSS reads a child of a channel in the RSS namespaces, so that
+// extensions with the same local names, such as media:title or
+// googleplay:description, do not overwrite the channel's own elements.
+func (ch *RSSChannel) decodeRSS(decoder *xml.Decoder, start xml.StartElement, limit *itemLimit) error {
+	switch start.Name.Local {
+	case "item":
+		item, err := decodeItem[RSSItem](decoder, start, limit)
+		if err != nil {
+			return err
+		}
+		ch.Item = append(ch.Item, item)
+		return nil
+	case "title":
+		return decoder.DecodeElement(&ch.Title, &start)
+	case "link":
+		return decoder.DecodeElement(&ch.Link, &start)
+	case "description":
+		return decoder.DecodeElement(&ch.Description, &start)
+	case "ttl":
+		return decoder.DecodeElement(&ch.TTL, &start)
+	case "skipHours":
+		var skip struct {
+			Hour []string `xml:"hour"`
+		}
+		err := decoder.DecodeElement(&skip, &start)
+		ch.SkipHours = skip.Hour
+		return err
+	case "skipDays":
+		var skip struct {
+			Day []string `xml:"day"`
+		}
+		err := decoder.DecodeElement(&skip, &start)
+		ch.SkipDays = skip.Day
+		return err
+	}
+	return decoder.Skip()
+}
@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestParseRSSIgnoresExtensionElements(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"
+	xmlns:googleplay="http://www.google.com/schemas/play-podcasts/1.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+	<title>Channel</title>
+	<media:title>Media title</media:title>
+	<link>https://example.com/</link>
+	<atom:link rel="self" href="https://example.com/feed"/>
+	<description>About</description>
+	<googleplay:description>Play description</googleplay:description>
+	<item>
+		<title>Episode</title>
+		<itunes:title>iTunes title</itunes:title>
+		<link>https://example.com/1</link>
+		<atom:link rel="self" href="https://example.com/1/feed"/>
+		<description>Notes</description>
+		<media:description>Media notes</media:description>
+		<author>ann@example.com (Ann)</author>
+		<itunes:author>Someone else</itunes:author>
+		<enclosure url="https://example.com/1.mp3" type="audio/mpeg" length="10"/>
+	</item>
+</channel>
+</rss>`
+	feed, err := parseFeed([]byte(doc), "application/rss+xml", 10)
+	if err != nil {
+		t.Fatalf("parseFeed failed: %v", err)
+	}
+	if feed.Title != "Channel" || feed.Link != "https://example.com/" || feed.Description != "About" {
+		t.Errorf("channel = %q, %q, %q", feed.Title, feed.Link, feed.Description)
+	}
+	if len(feed.Items) != 1 {
+		t.Fatalf("items = %+v, want one", feed.Items)
+	}
+	item := feed.Items[0]
+	if item.Title != "Episode" || item.Link != "https://example.com/1" || item.Description != "Notes" || item.Author != "ann@example.com (Ann)" {
+		t.Errorf("item = %+v", item)
+	}
+	if len(item.Enclosures) != 1 || item.Enclosures[0].URL != "https://example.com/1.mp3" {
+		t.Errorf("enclosures = %+v", item.Enclosures)
+	}
+}
+
+func TestParseRDF(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel rdf:about="https://example.com/"><title>Channel</title><link>https://example.com/</link></channel>
+<item rdf:about="https://example.com/1"><title>First</title><link>https://example.com/1</link><dc:date>2006-01-02T15:04:05Z</dc:date><dc:creator>Ann</dc:creator></item>
+</rdf:RDF>`
+	feed, err := parseFeed([]byte(doc), "", 10)
+	if err != nil {
+		t.Fatalf("parseFeed failed: %v", err)
+	}
+	if feed.Title != "Channel" || len(feed.Items) != 1 {
+		t.Fatalf("feed = %+v, want one item in a feed titled Channel", feed)
+	}
+	item := feed.Items[0]
+	if item.GUID != "https://example.com/1" || item.Title != "First" || item.PubDate != "2006-01-02T15:04:05Z" || item.Author != "Ann" {
+		t.Errorf("item = %+v", item)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var (
+	cdataStart = []byte("<![CDATA[")
+	cdataEnd   = []byte("]]>")
+	// entityOrAmpersand matches a well-formed entity or character
+	// reference, or a bare ampersand.
+	entityOrAmpersand = regexp.MustCompile(`&(?:[A-Za-z][A-Za-z0-9]*;|#[0-9]+;|#[xX][0-9A-Fa-f]+;)?`)
+)
+
+// sanitizeXML repairs the most common mistakes in real-world feeds before a
+// lenient parse: characters XML does not allow, invalid UTF-8 and unescaped
+// ampersands. CDATA sections are left as they are apart from the
+// characters.
+func sanitizeXML(dat []byte) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(dat))
+	rest := dat
+	for len(rest) > 0 {
+		i := bytes.Index(rest, cdataStart)
+		if i < 0 {
+			i = len(rest)
+		}
+		buf.Write(escapeBareAmpersands(stripInvalidChars(rest[:i])))
+		rest = rest[i:]
+		if len(rest) == 0 {
+			break
+		}
+		j := bytes.Index(rest, cdataEnd)
+		if j < 0 {
+			j = len(rest)
+		} else {
+			j += len(cdataEnd)
+		}
+		buf.Write(stripInvalidChars(rest[:j]))
+		rest = rest[j:]
+	}
+	return buf.Bytes()
+}
+
+// stripInvalidChars drops control characters that XML 1.0 forbids and
+// replaces invalid UTF-8 with U+FFFD.
+func stripInvalidChars(dat []byte) []byte {
+	return bytes.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case r < 0x20, r == 0xfffe, r == 0xffff:
+			return -1
+		}
+		return r
+	}, dat)
+}
+
+func escapeBareAmpersands(dat []byte) []byte {
+	return entityOrAmpersand.ReplaceAllFunc(dat, func(m []byte) []byte {
+		if len(m) == 1 {
+			return []byte("&amp;")
+		}
+		return m
+	})
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Corogura/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxHintedInterval caps how far a publisher's hints can push back the
+	// next fetch, so a bogus Expires or ttl cannot silence a feed for months.
+	maxHintedInterval = 24 * time.Hour
+	// failureBackoffBase is doubled for every consecutive failure.
+	failureBackoffBase = time.Minute
+	maxFailureBackoff  = 12
+	// claimLease keeps a claimed feed from being claimed again while it is
+	// being fetched. If agg dies mid-fetch the feed is due again afterwards.
+	claimLease = 5 * time.Minute
+	// postHistorySize is how many recent posts the adaptive interval is
+	// computed from.
+	postHistorySize = 20
+)
+
+// refreshHints are the publisher's suggestions on how often to poll a feed.
+type refreshHints struct {
+	// Interval comes from the RSS <ttl> or the Syndication module.
+	Interval  time.Duration
+	SkipHours []int
+	SkipDays  []time.Weekday
+}
+
+// rssChannelHints holds the refresh elements an RSS 0.9x/2.0 or RSS 1.0
+// channel may carry.
+type rssChannelHints struct {
+	TTL             string   `xml:"ttl"`
+	SkipHours       []string `xml:"skipHours>hour"`
+	SkipDays        []string `xml:"skipDays>day"`
+	UpdatePeriod    string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+func (h rssChannelHints) normalize() refreshHints {
+	var hints refreshHints
+	if ttl, err := strconv.Atoi(strings.TrimSpace(h.TTL)); err == nil && ttl > 0 {
+		hints.Interval = time.Duration(ttl) * time.Minute
+	} else if period, ok := updatePeriods[strings.ToLower(strings.TrimSpace(h.UpdatePeriod))]; ok {
+		frequency, err := strconv.Atoi(strings.TrimSpace(h.UpdateFrequency))
+		if err != nil || frequency < 1 {
+			frequency = 1
+		}
+		hints.Interval = period / time.Duration(frequency)
+	}
+	for _, hour := range h.SkipHours {
+		if n, err := strconv.Atoi(strings.TrimSpace(hour)); err == nil && n >= 0 && n < 24 {
+			hints.SkipHours = append(hints.SkipHours, n)
+		}
+	}
+	for _, day := range h.SkipDays {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.EqualFold(strings.TrimSpace(day), weekday.String()) {
+				hints.SkipDays = append(hints.SkipDays, weekday)
+			}
+		}
+	}
+	return hints
+}
+
+// storedHints returns the refresh hints saved on a feed by its last parsed
+// fetch.
+func storedHints(feed database.Feed) refreshHints {
+	hints := refreshHints{
+		Interval: time.Duration(feed.RefreshIntervalSeconds) * time.Second,
+	}
+	for _, hour := range feed.SkipHours {
+		hints.SkipHours = append(hints.SkipHours, int(hour))
+	}
+	for _, day := range feed.SkipDays {
+		hints.SkipDays = append(hints.SkipDays, time.Weekday(day))
+	}
+	return hints
+}
+
+// saveHints stores the refresh hints of a parsed feed document for the
+// fetches that answer 304 Not Modified.
+func saveHints(s *state, feedID uuid.UUID, hints refreshHints) error {
+	// Not nil: the columns are NOT NULL
+	skipHours, skipDays := []int32{}, []int32{}
+	for _, hour := range hints.SkipHours {
+		skipHours = append(skipHours, int32(hour))
+	}
+	for _, day := range hints.SkipDays {
+		skipDays = append(skipDays, int32(day))
+	}
+	err := s.db.SetFeedRefreshHints(context.Background(), database.SetFeedRefreshHintsParams{
+		ID:                     feedID,
+		RefreshIntervalSeconds: int32(min(hints.Interval, maxHintedInterval) / time.Second),
+		SkipHours:              skipHours,
+		SkipDays:               skipDays,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save refresh hints: %w", err)
+	}
+	return nil
+}
+
+// cacheExpiry returns when the response stops being fresh according to its
+// Cache-Control max-age or Expires header, or the zero time if neither is
+// usable.
+func cacheExpiry(header http.Header, now time.Time) time.Time {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive == "no-cache" || directive == "no-store" {
+			return time.Time{}
+		}
+		if value, ok := strings.CutPrefix(directive, "max-age="); ok {
+			if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+				return now.Add(time.Duration(seconds) * time.Second)
+			}
+		}
+	}
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		return expires
+	}
+	return time.Time{}
+}
+
+// adaptiveInterval derives a polling interval from the feed's posting
+// history: half the median gap between its recent posts, or half the time
+// since the latest post if the feed has gone quiet for longer than that.
+// postTimes must be sorted newest first. The result is clamped to
+// [minInterval, maxInterval].
+func adaptiveInterval(now time.Time, postTimes []time.Time, minInterval, maxInterval time.Duration) time.Duration {
+	if len(postTimes) == 0 {
+		return minInterval
+	}
+	var gaps []time.Duration
+	for i := 1; i < len(postTimes); i++ {
+		gaps = append(gaps, postTimes[i-1].Sub(postTimes[i]))
+	}
+	interval := now.Sub(postTimes[0])
+	if len(gaps) > 0 {
+		slices.Sort(gaps)
+		interval = max(gaps[len(gaps)/2], interval)
+	}
+	return min(max(interval/2, minInterval), maxInterval)
+}
+
+// nextFetchAt schedules the next fetch of a feed that was just fetched
+// successfully: after interval, unless the feed's hints or the HTTP cache
+// headers ask for a later time.
+func nextFetchAt(now time.Time, interval time.Duration, hints refreshHints, cacheUntil time.Time) time.Time {
+	hinted := now.Add(hints.Interval)
+	if cacheUntil.After(hinted) {
+		hinted = cacheUntil
+	}
+	if hinted.After(now.Add(maxHintedInterval)) {
+		hinted = now.Add(maxHintedInterval)
+	}
+	next := now.Add(interval)
+	if hinted.After(next) {
+		next = hinted
+	}
+	return skipHinted(next, hints)
+}
+
+// skipHinted moves t forward to the first hour that is in neither
+// skipHours nor skipDays. Both are expressed in GMT.
+func skipHinted(t time.Time, hints refreshHints) time.Time {
+	skipped := func(t time.Time) bool {
+		utc := t.UTC()
+		for _, hour := range hints.SkipHours {
+			if utc.Hour() == hour {
+				return true
+			}
+		}
+		for _, day := range hints.SkipDays {
+			if utc.Weekday() == day {
+				return true
+			}
+		}
+		return false
+	}
+	// A week of hours covers every combination; if all of them are skipped
+	// the hints are nonsense and are ignored.
+	next := t
+	for i := 0; i < 7*24; i++ {
+		if !skipped(next) {
+			return next
+		}
+		next = next.UTC().Truncate(time.Hour).Add(time.Hour)
+	}
+	return t
+}
+
+// failureBackoff is how long to wait before retrying a feed that has failed
+// failures times in a row.
+func failureBackoff(failures int32) time.Duration {
+	return failureBackoffBase << min(failures, maxFailureBackoff)
+}
@@ -0,0 +1,94 @@
+-- name: GetDownloadCandidates :many
+SELECT
+    enclosures.*,
+    posts.feed_id,
+    posts.title AS post_title,
+    feeds.name AS feed_name,
+    feed_follows.user_id,
+    feed_follows.keep_last,
+    EXISTS (
+        SELECT 1 FROM saved_posts
+        WHERE saved_posts.user_id = feed_follows.user_id
+            AND saved_posts.post_id = posts.id
+    ) AS starred
+FROM enclosures
+INNER JOIN posts ON enclosures.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE enclosures.mime_type IS NULL
+    OR enclosures.mime_type LIKE 'audio/%'
+    OR enclosures.mime_type LIKE 'video/%'
+ORDER BY feed_follows.user_id, posts.feed_id, COALESCE(posts.published_at, posts.created_at) DESC, posts.id, enclosures.mime_type IS NULL, enclosures.url;
+
+-- name: GetDownload :one
+SELECT * FROM downloads
+WHERE enclosure_id = $1;
+
+-- name: GetCompletedDownloadsForUser :many
+SELECT downloads.*
+FROM downloads
+INNER JOIN enclosures ON downloads.enclosure_id = enclosures.id
+INNER JOIN posts ON enclosures.post_id = posts.id
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+    AND downloads.status = 'done';
+
+-- name: StartDownload :one
+INSERT INTO downloads (id, created_at, updated_at, enclosure_id, path, status)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    'downloading'
+)
+ON CONFLICT (enclosure_id) DO UPDATE
+SET status = 'downloading',
+    error = NULL,
+    updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: FinishDownload :exec
+UPDATE downloads
+SET status = 'done',
+    bytes = $1,
+    sha256 = $2,
+    validator = $3,
+    error = NULL,
+    completed_at = $4,
+    updated_at = $4
+WHERE id = $5;
+
+-- name: FailDownload :exec
+UPDATE downloads
+SET status = 'failed',
+    bytes = $1,
+    validator = $2,
+    error = $3,
+    updated_at = $4
+WHERE id = $5;
+
+-- name: MarkDownloadDeleted :exec
+UPDATE downloads
+SET status = 'deleted',
+    updated_at = $1
+WHERE id = $2;
+
+-- name: MoveDownloads :exec
+-- Hands the downloads of the posts a merged feed shares with the feed it is
+-- merged into over to the same enclosures of that feed's copies.
+UPDATE downloads
+SET enclosure_id = target.id
+FROM enclosures
+INNER JOIN posts ON enclosures.post_id = posts.id
+INNER JOIN posts AS target_post ON target_post.guid = posts.guid
+INNER JOIN enclosures AS target ON target.post_id = target_post.id
+    AND target.url = enclosures.url
+WHERE downloads.enclosure_id = enclosures.id
+    AND posts.feed_id = sqlc.arg(from_feed_id)
+    AND target_post.feed_id = sqlc.arg(to_feed_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM downloads AS existing
+        WHERE existing.enclosure_id = target.id
+    );
\ No newline at end of file
@@ -0,0 +1,30 @@
+-- name: UpsertEnclosure :exec
+INSERT INTO enclosures (id, created_at, updated_at, post_id, url, mime_type, length, duration, image)
+SELECT
+    sqlc.arg(id)::uuid,
+    sqlc.arg(created_at)::timestamptz,
+    sqlc.arg(created_at)::timestamptz,
+    posts.id,
+    sqlc.arg(url)::text,
+    sqlc.narg(mime_type)::text,
+    sqlc.narg(length)::bigint,
+    sqlc.narg(duration)::int,
+    sqlc.narg(image)::text
+FROM posts
+WHERE posts.feed_id = sqlc.arg(feed_id)
+    AND posts.guid = sqlc.arg(guid)
+ON CONFLICT (post_id, url) DO UPDATE
+SET mime_type = EXCLUDED.mime_type,
+    length = EXCLUDED.length,
+    duration = EXCLUDED.duration,
+    image = EXCLUDED.image,
+    updated_at = EXCLUDED.updated_at
+WHERE enclosures.mime_type IS DISTINCT FROM EXCLUDED.mime_type
+    OR enclosures.length IS DISTINCT FROM EXCLUDED.length
+    OR enclosures.duration IS DISTINCT FROM EXCLUDED.duration
+    OR enclosures.image IS DISTINCT FROM EXCLUDED.image;
+
+-- name: GetEnclosuresForPost :many
+SELECT * FROM enclosures
+WHERE post_id = $1
+ORDER BY created_at, url;
\ No newline at end of file
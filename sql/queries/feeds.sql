@@ -17,14 +17,89 @@ SELECT * FROM feeds;
 SELECT * FROM feeds
 WHERE url = $1;
 
--- name: MarkFeedFetched :one
+-- name: GetNextFeedsToFetch :many
 UPDATE feeds
 SET last_fetched_at = $1,
+    updated_at = $2,
+    next_fetch_at = $4
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE disabled_at IS NULL
+        AND (next_fetch_at IS NULL OR next_fetch_at <= $1)
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: SetFeedCacheValidators :exec
+UPDATE feeds
+SET etag = $1,
+    last_modified = $2
+WHERE id = $3;
+
+-- name: RecordFeedFailure :one
+UPDATE feeds
+SET last_error = sqlc.arg(last_error),
+    last_error_at = sqlc.arg(last_error_at),
+    consecutive_failures = consecutive_failures + 1,
+    next_fetch_at = sqlc.arg(next_fetch_at),
+    disabled_at = CASE
+        WHEN sqlc.arg(max_failures)::int > 0 AND consecutive_failures + 1 >= sqlc.arg(max_failures)::int THEN sqlc.arg(last_error_at)
+        ELSE disabled_at
+    END
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+    last_error = NULL,
+    last_error_at = NULL,
+    next_fetch_at = $1
+WHERE id = $2;
+
+-- name: EnableFeed :exec
+UPDATE feeds
+SET disabled_at = NULL,
+    gone_at = NULL,
+    consecutive_failures = 0,
+    next_fetch_at = NULL,
+    updated_at = $1
+WHERE id = $2;
+
+-- name: SetFeedParseWarning :exec
+UPDATE feeds
+SET parse_warning = $1
+WHERE id = $2;
+
+-- name: SetFeedRefreshHints :exec
+UPDATE feeds
+SET refresh_interval_seconds = $1,
+    skip_hours = $2,
+    skip_days = $3
+WHERE id = $4;
+
+-- name: UpdateFeedURL :one
+UPDATE feeds
+SET url = $1,
     updated_at = $2
 WHERE id = $3
 RETURNING *;
 
--- name: GetNextFeedToFetch :one
-SELECT * FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+-- name: MarkFeedGone :exec
+UPDATE feeds
+SET gone_at = $1,
+    disabled_at = $1
+WHERE id = $2;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: DeferFeedFetch :exec
+UPDATE feeds
+SET last_error = $1,
+    last_error_at = $2,
+    next_fetch_at = $3
+WHERE id = $4;
\ No newline at end of file
@@ -30,4 +30,17 @@ WHERE feed_follows.user_id = $1;
 
 -- name: Unfollow :exec
 DELETE FROM feed_follows
-WHERE user_id = $1 AND feed_id = $2;
\ No newline at end of file
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: MoveFeedFollows :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, keep_last)
+SELECT gen_random_uuid(), created_at, sqlc.arg(updated_at)::timestamptz, user_id, sqlc.arg(to_feed_id)::uuid, keep_last
+FROM feed_follows
+WHERE feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+-- name: SetFollowKeepLast :execrows
+UPDATE feed_follows
+SET keep_last = $1,
+    updated_at = $2
+WHERE user_id = $3 AND feed_id = $4;
\ No newline at end of file
@@ -1,5 +1,37 @@
--- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+-- name: GetRecentPostTimes :many
+SELECT COALESCE(published_at, created_at)::timestamptz AS posted_at
+FROM posts
+WHERE feed_id = $1
+ORDER BY posted_at DESC
+LIMIT $2;
+
+-- name: MovePosts :exec
+UPDATE posts
+SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM posts AS existing
+        WHERE existing.feed_id = sqlc.arg(to_feed_id)
+            AND existing.guid = posts.guid
+    );
+
+-- name: AdoptLegacyPost :exec
+-- Posts stored before guids were tracked were given their URL as guid.
+-- Giving such a post the item's guid lets the upsert update it instead of
+-- adding a duplicate.
+UPDATE posts
+SET guid = sqlc.arg(guid)
+WHERE feed_id = sqlc.arg(feed_id)
+    AND url = sqlc.arg(url)
+    AND guid = url
+    AND NOT EXISTS (
+        SELECT 1 FROM posts AS existing
+        WHERE existing.feed_id = sqlc.arg(feed_id)
+            AND existing.guid = sqlc.arg(guid)
+    );
+
+-- name: UpsertPost :execrows
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content, author, comments_url)
 VALUES (
     $1,
     $2,
@@ -8,17 +40,64 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11,
+    $12
 )
-RETURNING *;
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    author = EXCLUDED.author,
+    comments_url = EXCLUDED.comments_url,
+    updated_at = EXCLUDED.updated_at
+WHERE posts.title <> EXCLUDED.title
+    OR posts.url <> EXCLUDED.url
+    OR posts.description <> EXCLUDED.description
+    OR posts.content <> EXCLUDED.content
+    OR posts.author <> EXCLUDED.author
+    OR posts.comments_url <> EXCLUDED.comments_url;
 
 -- name: GetPostsForUser :many
 SELECT
-    posts.*,
+    posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content, posts.author, posts.comments_url,
     feeds.name AS feed_name
 FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
 INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND (sqlc.narg(author)::text IS NULL OR posts.author ILIKE '%' || sqlc.narg(author) || '%')
+    AND (sqlc.narg(category)::text IS NULL OR EXISTS (
+        SELECT 1 FROM post_categories
+        WHERE post_categories.post_id = posts.id
+            AND lower(post_categories.name) = lower(sqlc.narg(category))
+    ))
+    AND (sqlc.arg(include_read)::boolean OR NOT EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id
+            AND post_reads.post_id = posts.id
+    ))
 ORDER BY COALESCE(posts.published_at, posts.created_at) DESC
-LIMIT $2;
\ No newline at end of file
+LIMIT sqlc.arg(max_posts);
+
+-- name: GetPostCategories :many
+SELECT name FROM post_categories
+WHERE post_id = $1
+ORDER BY name;
+
+-- name: SetPostCategories :exec
+WITH post AS (
+    SELECT id FROM posts
+    WHERE feed_id = sqlc.arg(feed_id) AND guid = sqlc.arg(guid)
+), removed AS (
+    DELETE FROM post_categories
+    WHERE post_id IN (SELECT id FROM post)
+        AND NOT (name = ANY(sqlc.arg(names)::text[]))
+)
+INSERT INTO post_categories (post_id, name)
+SELECT post.id, unnest(sqlc.arg(names)::text[])
+FROM post
+ON CONFLICT DO NOTHING;
\ No newline at end of file
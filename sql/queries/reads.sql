@@ -0,0 +1,30 @@
+-- name: MarkPostRead :execrows
+-- The no-op update keeps the first read time but still counts the row.
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT sqlc.arg(user_id)::uuid, posts.id, sqlc.arg(read_at)::timestamptz
+FROM posts
+WHERE posts.id = sqlc.arg(post_id)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = post_reads.read_at;
+
+-- name: MarkPostsRead :execrows
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT feed_follows.user_id, posts.id, sqlc.arg(read_at)::timestamptz
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+    AND (sqlc.narg(before)::timestamptz IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(before))
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MovePostReads :exec
+-- Carries the reads of the posts a merged feed shares with the feed it is
+-- merged into over to that feed's copies.
+INSERT INTO post_reads (user_id, post_id, read_at)
+SELECT post_reads.user_id, target.id, post_reads.read_at
+FROM post_reads
+INNER JOIN posts ON post_reads.post_id = posts.id
+INNER JOIN posts AS target ON target.guid = posts.guid
+WHERE posts.feed_id = sqlc.arg(from_feed_id)
+    AND target.feed_id = sqlc.arg(to_feed_id)
+ON CONFLICT (user_id, post_id) DO NOTHING;
\ No newline at end of file
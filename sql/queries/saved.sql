@@ -0,0 +1,40 @@
+-- name: StarPost :execrows
+-- Starring a post again only changes its note, and only when one is given.
+INSERT INTO saved_posts (user_id, post_id, created_at, note)
+SELECT sqlc.arg(user_id)::uuid, posts.id, sqlc.arg(created_at)::timestamptz, COALESCE(sqlc.narg(note)::text, '')
+FROM posts
+WHERE posts.id = sqlc.arg(post_id)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET note = COALESCE(sqlc.narg(note)::text, saved_posts.note);
+
+-- name: UnstarPost :execrows
+DELETE FROM saved_posts
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetSavedPostsForUser :many
+SELECT
+    saved_posts.created_at AS starred_at,
+    saved_posts.note,
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name
+FROM saved_posts
+INNER JOIN posts ON saved_posts.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE saved_posts.user_id = $1
+ORDER BY saved_posts.created_at DESC;
+
+-- name: MoveSavedPosts :exec
+-- Carries the stars of the posts a merged feed shares with the feed it is
+-- merged into over to that feed's copies, keeping a note of either.
+INSERT INTO saved_posts (user_id, post_id, created_at, note)
+SELECT saved_posts.user_id, target.id, saved_posts.created_at, saved_posts.note
+FROM saved_posts
+INNER JOIN posts ON saved_posts.post_id = posts.id
+INNER JOIN posts AS target ON target.guid = posts.guid
+WHERE posts.feed_id = sqlc.arg(from_feed_id)
+    AND target.feed_id = sqlc.arg(to_feed_id)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET note = CASE WHEN saved_posts.note = '' THEN EXCLUDED.note ELSE saved_posts.note END;
\ No newline at end of file
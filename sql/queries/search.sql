@@ -0,0 +1,27 @@
+-- name: SearchPosts :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    ts_headline(
+        'english',
+        CASE WHEN posts.description <> '' THEN posts.description ELSE posts.content END,
+        query,
+        'StartSel=**, StopSel=**, MaxWords=35, MinWords=15'
+    )::text AS snippet
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+CROSS JOIN websearch_to_tsquery('english', sqlc.arg(query)) AS query
+WHERE posts.search_vector @@ query
+    AND (sqlc.arg(all_feeds)::boolean OR EXISTS (
+        SELECT 1 FROM feed_follows
+        WHERE feed_follows.feed_id = posts.feed_id
+            AND feed_follows.user_id = sqlc.arg(user_id)
+    ))
+    AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+    AND (sqlc.narg(since)::timestamptz IS NULL OR COALESCE(posts.published_at, posts.created_at) >= sqlc.narg(since))
+    AND (sqlc.narg(until)::timestamptz IS NULL OR COALESCE(posts.published_at, posts.created_at) < sqlc.narg(until))
+ORDER BY ts_rank(posts.search_vector, query) DESC, COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT sqlc.arg(max_posts);
\ No newline at end of file
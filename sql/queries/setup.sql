@@ -1,20 +1,32 @@
 -- name: SetupUsers :exec
 CREATE TABLE IF NOT EXISTS users(
     id UUID PRIMARY KEY,
-    created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
     name TEXT NOT NULL UNIQUE
 );
 
 -- name: SetupFeeds :exec
 CREATE TABLE IF NOT EXISTS feeds(
     id UUID PRIMARY KEY,
-    created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
     name TEXT NOT NULL,
     url TEXT NOT NULL UNIQUE,
     user_id UUID NOT NULL,
-    last_fetched_at TIMESTAMP,
+    last_fetched_at TIMESTAMPTZ,
+    etag TEXT,
+    last_modified TEXT,
+    last_error TEXT,
+    last_error_at TIMESTAMPTZ,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMPTZ,
+    next_fetch_at TIMESTAMPTZ,
+    gone_at TIMESTAMPTZ,
+    parse_warning TEXT,
+    refresh_interval_seconds INTEGER NOT NULL DEFAULT 0,
+    skip_hours INTEGER[] NOT NULL DEFAULT '{}',
+    skip_days INTEGER[] NOT NULL DEFAULT '{}',
     CONSTRAINT fk_user
         FOREIGN KEY(user_id) 
         REFERENCES users(id)
@@ -24,10 +36,11 @@ CREATE TABLE IF NOT EXISTS feeds(
 -- name: SetupFeedFollows :exec
 CREATE TABLE IF NOT EXISTS feed_follows(
     id UUID PRIMARY KEY,
-    created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
     user_id UUID NOT NULL,
     feed_id UUID NOT NULL,
+    keep_last INTEGER,
     CONSTRAINT fk_user_follow
         FOREIGN KEY(user_id) 
         REFERENCES users(id)
@@ -42,15 +55,122 @@ CREATE TABLE IF NOT EXISTS feed_follows(
 -- name: SetupPosts :exec
 CREATE TABLE IF NOT EXISTS posts (
     id UUID PRIMARY KEY,
-    created_at TIMESTAMP NOT NULL,
-    updated_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
     title TEXT NOT NULL,
-    url TEXT NOT NULL UNIQUE,
+    url TEXT NOT NULL,
     description TEXT NOT NULL,
-    published_at TIMESTAMP,
+    published_at TIMESTAMPTZ,
     feed_id UUID NOT NULL,
+    guid TEXT NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    author TEXT NOT NULL DEFAULT '',
+    comments_url TEXT NOT NULL DEFAULT '',
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', title), 'A') ||
+        setweight(to_tsvector('english', description), 'B') ||
+        setweight(to_tsvector('english', content), 'C')
+    ) STORED,
     CONSTRAINT fk_feed
         FOREIGN KEY(feed_id) 
         REFERENCES feeds(id)
+        ON DELETE CASCADE,
+    UNIQUE(feed_id, guid)
+);
+
+-- name: SetupFeedCredentials :exec
+CREATE TABLE IF NOT EXISTS feed_credentials (
+    feed_id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    auth_type TEXT NOT NULL,
+    secret BYTEA NOT NULL,
+    CONSTRAINT fk_feed_credentials
+        FOREIGN KEY(feed_id) 
+        REFERENCES feeds(id)
+        ON DELETE CASCADE
+);
+
+-- name: SetupEnclosures :exec
+CREATE TABLE IF NOT EXISTS enclosures (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    post_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    mime_type TEXT,
+    length BIGINT,
+    duration INTEGER,
+    image TEXT,
+    CONSTRAINT fk_post
+        FOREIGN KEY(post_id) 
+        REFERENCES posts(id)
+        ON DELETE CASCADE,
+    UNIQUE(post_id, url)
+);
+
+-- name: SetupDownloads :exec
+CREATE TABLE IF NOT EXISTS downloads (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    enclosure_id UUID NOT NULL UNIQUE,
+    path TEXT NOT NULL,
+    status TEXT NOT NULL,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    sha256 TEXT,
+    error TEXT,
+    completed_at TIMESTAMPTZ,
+    validator TEXT,
+    CONSTRAINT fk_enclosure
+        FOREIGN KEY(enclosure_id) 
+        REFERENCES enclosures(id)
+        ON DELETE CASCADE
+);
+
+-- name: SetupPostCategories :exec
+CREATE TABLE IF NOT EXISTS post_categories (
+    post_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    PRIMARY KEY(post_id, name),
+    CONSTRAINT fk_post
+        FOREIGN KEY(post_id) 
+        REFERENCES posts(id)
         ON DELETE CASCADE
-);
\ No newline at end of file
+);
+
+-- name: SetupPostReads :exec
+CREATE TABLE IF NOT EXISTS post_reads (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    read_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY(user_id, post_id),
+    CONSTRAINT fk_user_read
+        FOREIGN KEY(user_id) 
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_post_read
+        FOREIGN KEY(post_id) 
+        REFERENCES posts(id)
+        ON DELETE CASCADE
+);
+
+-- name: SetupSavedPosts :exec
+CREATE TABLE IF NOT EXISTS saved_posts (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY(user_id, post_id),
+    CONSTRAINT fk_user_saved
+        FOREIGN KEY(user_id) 
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_post_saved
+        FOREIGN KEY(post_id) 
+        REFERENCES posts(id)
+        ON DELETE CASCADE
+);
+
+-- name: SetupPostSearchIndex :exec
+CREATE INDEX IF NOT EXISTS posts_search_idx ON posts USING GIN (search_vector);
\ No newline at end of file
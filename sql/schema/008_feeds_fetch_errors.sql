@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+    ADD COLUMN last_error TEXT,
+    ADD COLUMN last_error_at TIMESTAMP,
+    ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN disabled_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN last_error,
+    DROP COLUMN last_error_at,
+    DROP COLUMN consecutive_failures,
+    DROP COLUMN disabled_at;
\ No newline at end of file
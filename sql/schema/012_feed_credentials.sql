@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE feed_credentials (
+    feed_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    auth_type TEXT NOT NULL,
+    secret BYTEA NOT NULL,
+    CONSTRAINT fk_feed
+        FOREIGN KEY(feed_id) 
+        REFERENCES feeds(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_credentials;
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE feed_follows
+    ADD COLUMN keep_last INTEGER;
+
+CREATE TABLE downloads (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    enclosure_id UUID NOT NULL UNIQUE,
+    path TEXT NOT NULL,
+    status TEXT NOT NULL,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    sha256 TEXT,
+    error TEXT,
+    completed_at TIMESTAMP,
+    CONSTRAINT fk_enclosure
+        FOREIGN KEY(enclosure_id) 
+        REFERENCES enclosures(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE downloads;
+
+ALTER TABLE feed_follows
+    DROP COLUMN keep_last;
\ No newline at end of file
@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE posts
+    ADD COLUMN content TEXT NOT NULL DEFAULT '',
+    ADD COLUMN author TEXT NOT NULL DEFAULT '',
+    ADD COLUMN comments_url TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE post_categories (
+    post_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    PRIMARY KEY(post_id, name),
+    CONSTRAINT fk_post
+        FOREIGN KEY(post_id) 
+        REFERENCES posts(id)
+        ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE post_categories;
+
+ALTER TABLE posts
+    DROP COLUMN content,
+    DROP COLUMN author,
+    DROP COLUMN comments_url;
\ No newline at end of file
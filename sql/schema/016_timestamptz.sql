@@ -0,0 +1,76 @@
+-- +goose Up
+-- Earlier versions wrote the wall-clock time of the machine running gator,
+-- without its zone, and published_at in the zone of the feed. The values
+-- are read in the session's TimeZone, so run this migration with it set to
+-- that machine's zone, e.g. with timezone=Europe/Paris in the connection
+-- string.
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE feeds
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN last_fetched_at TYPE TIMESTAMPTZ USING last_fetched_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN last_error_at TYPE TIMESTAMPTZ USING last_error_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN disabled_at TYPE TIMESTAMPTZ USING disabled_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN next_fetch_at TYPE TIMESTAMPTZ USING next_fetch_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN gone_at TYPE TIMESTAMPTZ USING gone_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE feed_follows
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE posts
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN published_at TYPE TIMESTAMPTZ USING published_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE feed_credentials
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE enclosures
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE downloads
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ USING updated_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN completed_at TYPE TIMESTAMPTZ USING completed_at AT TIME ZONE current_setting('TimeZone');
+
+-- +goose Down
+ALTER TABLE downloads
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN completed_at TYPE TIMESTAMP USING completed_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE enclosures
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE feed_credentials
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE posts
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN published_at TYPE TIMESTAMP USING published_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE feed_follows
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE feeds
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN last_fetched_at TYPE TIMESTAMP USING last_fetched_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN last_error_at TYPE TIMESTAMP USING last_error_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN disabled_at TYPE TIMESTAMP USING disabled_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN next_fetch_at TYPE TIMESTAMP USING next_fetch_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN gone_at TYPE TIMESTAMP USING gone_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE users
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN updated_at TYPE TIMESTAMP USING updated_at AT TIME ZONE current_setting('TimeZone');
\ No newline at end of file
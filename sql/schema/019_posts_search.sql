@@ -0,0 +1,17 @@
+-- +goose Up
+-- Matches in the title rank above those in the description, and those
+-- above matches in the content
+ALTER TABLE posts
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', title), 'A') ||
+        setweight(to_tsvector('english', description), 'B') ||
+        setweight(to_tsvector('english', content), 'C')
+    ) STORED;
+
+CREATE INDEX posts_search_idx ON posts USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX posts_search_idx;
+
+ALTER TABLE posts
+    DROP COLUMN search_vector;
\ No newline at end of file
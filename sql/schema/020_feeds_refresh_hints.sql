@@ -0,0 +1,13 @@
+-- +goose Up
+-- The refresh hints of the last parsed document, kept for fetches that
+-- answer 304 Not Modified. skip_days holds weekdays, Sunday being 0.
+ALTER TABLE feeds
+    ADD COLUMN refresh_interval_seconds INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN skip_hours INTEGER[] NOT NULL DEFAULT '{}',
+    ADD COLUMN skip_days INTEGER[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE feeds
+    DROP COLUMN refresh_interval_seconds,
+    DROP COLUMN skip_hours,
+    DROP COLUMN skip_days;
\ No newline at end of file
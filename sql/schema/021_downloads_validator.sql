@@ -0,0 +1,9 @@
+-- +goose Up
+-- The ETag or Last-Modified of the response a download was started from,
+-- sent as If-Range when resuming it.
+ALTER TABLE downloads
+    ADD COLUMN validator TEXT;
+
+-- +goose Down
+ALTER TABLE downloads
+    DROP COLUMN validator;
\ No newline at end of file